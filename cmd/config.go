@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/myuon/gallon/gallon"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	ConfigCmd.AddCommand(ConfigSchemaCmd)
+}
+
+// ConfigCmd defines `gallon config` command.
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Commands for config files",
+}
+
+// ConfigSchemaCmd defines `gallon config schema` command.
+var ConfigSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of config files",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(gallon.ConfigJSONSchema())
+	},
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/myuon/gallon/gallon"
+	"github.com/spf13/cobra"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// InitOptions are the options of `gallon init`.
+type InitOptions struct {
+	// From is the type of the input plugin, `sql` or `dynamodb`.
+	From string
+	// To is the type of the output plugin, `bigquery`.
+	To string
+
+	// Table is the table of the source.
+	Table string
+	// Driver and Dsn are for `sql`.
+	Driver string
+	Dsn    string
+	// Region and Endpoint are for `dynamodb`, and Samples is the number of items scanned to infer the schema.
+	Region   string
+	Endpoint string
+	Samples  int
+
+	// ProjectId, DatasetId, TableId (default: Table) and Location are for `bigquery`.
+	ProjectId string
+	DatasetId string
+	TableId   string
+	Location  string
+}
+
+var initOptions InitOptions
+var initOutput string
+
+func init() {
+	InitCmd.Flags().StringVar(&initOptions.From, "from", "", "type of the input plugin (sql or dynamodb)")
+	InitCmd.Flags().StringVar(&initOptions.To, "to", "bigquery", "type of the output plugin (bigquery)")
+	InitCmd.Flags().StringVar(&initOptions.Table, "table", "", "table of the source")
+	InitCmd.Flags().StringVar(&initOptions.Driver, "driver", "", "database driver for --from sql (mysql or postgres)")
+	InitCmd.Flags().StringVar(&initOptions.Dsn, "dsn", "", "database url for --from sql")
+	InitCmd.Flags().StringVar(&initOptions.Region, "region", "", "AWS region for --from dynamodb")
+	InitCmd.Flags().StringVar(&initOptions.Endpoint, "endpoint", "", "DynamoDB endpoint for --from dynamodb (optional)")
+	InitCmd.Flags().IntVar(&initOptions.Samples, "samples", 100, "number of items scanned to infer the schema for --from dynamodb")
+	InitCmd.Flags().StringVar(&initOptions.ProjectId, "project", "", "GCP project ID for --to bigquery")
+	InitCmd.Flags().StringVar(&initOptions.DatasetId, "dataset", "", "BigQuery dataset ID for --to bigquery")
+	InitCmd.Flags().StringVar(&initOptions.TableId, "bigquery-table", "", "BigQuery table ID for --to bigquery (default: --table)")
+	InitCmd.Flags().StringVar(&initOptions.Location, "location", "", "BigQuery location for --to bigquery (optional)")
+	InitCmd.Flags().StringVarP(&initOutput, "output", "o", "", "write the config to the file instead of stdout")
+
+	_ = InitCmd.MarkFlagRequired("from")
+	_ = InitCmd.MarkFlagRequired("table")
+}
+
+// InitCmd defines `gallon init` command.
+var InitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Generate a config file with the schema read from the source",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configYml, err := InitGallonConfig(cmd.Context(), initOptions)
+		if err != nil {
+			return err
+		}
+
+		if initOutput == "" {
+			_, err := cmd.OutOrStdout().Write(configYml)
+			return err
+		}
+
+		return os.WriteFile(initOutput, configYml, 0666)
+	},
+}
+
+type initConfig struct {
+	In  any                `yaml:"in"`
+	Out initBigQueryOutput `yaml:"out"`
+}
+
+type initSqlInput struct {
+	Type        string                                                                  `yaml:"type"`
+	Driver      string                                                                  `yaml:"driver"`
+	DatabaseUrl string                                                                  `yaml:"database_url"`
+	Table       string                                                                  `yaml:"table"`
+	Schema      *orderedmap.OrderedMap[string, gallon.InputPluginSqlConfigSchemaColumn] `yaml:"schema"`
+}
+
+type initDynamoDbInput struct {
+	Type     string                                                  `yaml:"type"`
+	Region   string                                                  `yaml:"region"`
+	Endpoint string                                                  `yaml:"endpoint,omitempty"`
+	Table    string                                                  `yaml:"table"`
+	Schema   map[string]gallon.InputPluginDynamoDbConfigSchemaColumn `yaml:"schema"`
+}
+
+type initBigQueryOutput struct {
+	Type      string                                             `yaml:"type"`
+	ProjectId string                                             `yaml:"projectId"`
+	DatasetId string                                             `yaml:"datasetId"`
+	TableId   string                                             `yaml:"tableId"`
+	Location  string                                             `yaml:"location,omitempty"`
+	Schema    *orderedmap.OrderedMap[string, initBigQueryColumn] `yaml:"schema"`
+}
+
+// initBigQueryColumn is gallon.OutputPluginBigQueryConfigSchemaColumn with the pointer of the ordered map, which is needed to marshal it.
+type initBigQueryColumn struct {
+	Type     string                                             `yaml:"type"`
+	Repeated bool                                               `yaml:"repeated,omitempty"`
+	Fields   *orderedmap.OrderedMap[string, initBigQueryColumn] `yaml:"fields,omitempty"`
+}
+
+// InitGallonConfig reads the schema of the source, and returns a config yaml with the input schema and the matching output schema.
+func InitGallonConfig(ctx context.Context, opts InitOptions) ([]byte, error) {
+	if opts.To != "bigquery" {
+		return nil, fmt.Errorf("unsupported output plugin: %v (supported: bigquery)", opts.To)
+	}
+
+	var config initConfig
+	switch opts.From {
+	case "sql":
+		if opts.Driver == "" || opts.Dsn == "" {
+			return nil, errors.New("driver and dsn are required for sql")
+		}
+
+		schema, err := gallon.InspectSqlSchema(ctx, gallon.InputPluginSqlConfig{
+			Driver:      opts.Driver,
+			DatabaseUrl: opts.Dsn,
+			Table:       opts.Table,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		config.In = initSqlInput{
+			Type:        "sql",
+			Driver:      opts.Driver,
+			DatabaseUrl: opts.Dsn,
+			Table:       opts.Table,
+			Schema:      schema,
+		}
+	case "dynamodb":
+		var endpoint *string
+		if opts.Endpoint != "" {
+			endpoint = &opts.Endpoint
+		}
+
+		schema, err := gallon.InspectDynamoDbSchema(ctx, gallon.InputPluginDynamoDbConfig{
+			Region:   opts.Region,
+			Endpoint: endpoint,
+			Table:    opts.Table,
+		}, opts.Samples)
+		if err != nil {
+			return nil, err
+		}
+
+		config.In = initDynamoDbInput{
+			Type:     "dynamodb",
+			Region:   opts.Region,
+			Endpoint: opts.Endpoint,
+			Table:    opts.Table,
+			Schema:   schema,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported input plugin: %v (supported: sql, dynamodb)", opts.From)
+	}
+
+	inYml, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// the output schema is the one inferred by the bigquery output plugin
+	inSchema, err := gallon.InputSchema(inYml)
+	if err != nil {
+		return nil, err
+	}
+
+	tableId := opts.TableId
+	if tableId == "" {
+		tableId = opts.Table
+	}
+
+	config.Out = initBigQueryOutput{
+		Type:      "bigquery",
+		ProjectId: opts.ProjectId,
+		DatasetId: opts.DatasetId,
+		TableId:   tableId,
+		Location:  opts.Location,
+		Schema:    initBigQuerySchema(gallon.InferBigQuerySchema(*inSchema)),
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func initBigQuerySchema(schema bigquery.Schema) *orderedmap.OrderedMap[string, initBigQueryColumn] {
+	columns := orderedmap.New[string, initBigQueryColumn]()
+	for _, field := range schema {
+		column := initBigQueryColumn{
+			Type:     strings.ToLower(string(field.Type)),
+			Repeated: field.Repeated,
+		}
+		if field.Type == bigquery.RecordFieldType {
+			column.Fields = initBigQuerySchema(field.Schema)
+		}
+
+		columns.Set(field.Name, column)
+	}
+
+	return columns
+}
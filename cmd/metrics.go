@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/myuon/gallon/gallon"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// serveMetrics serves the Prometheus metrics at `/metrics` of the address, until the returned function is called.
+func serveMetrics(addr string) (func(), error) {
+	if err := gallon.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+		return nil, err
+	}
+
+	// listen before returning, so that an invalid address fails the command
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zap.S().Errorw("Failed to serve metrics", "error", err)
+		}
+	}()
+
+	zap.S().Infow("Serving metrics", "addr", listener.Addr().String())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := server.Shutdown(ctx); err != nil {
+			zap.S().Errorw("Failed to shutdown metrics server", "error", err)
+		}
+	}, nil
+}
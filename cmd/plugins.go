@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/myuon/gallon/gallon"
+	"github.com/spf13/cobra"
+)
+
+// PluginsCmd defines `gallon plugins` command.
+var PluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "List the registered plugins",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "input:     %v\n", strings.Join(gallon.InputPlugins(), ", "))
+		fmt.Fprintf(out, "output:    %v\n", strings.Join(gallon.OutputPlugins(), ", "))
+		fmt.Fprintf(out, "transform: %v\n", strings.Join(gallon.TransformPlugins(), ", "))
+
+		return nil
+	},
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-logr/zapr"
+	"github.com/myuon/gallon/gallon"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+var previewLimit int
+var previewFormat string
+
+func init() {
+	PreviewCmd.Flags().BoolVar(&withTemplate, "template", false, "parse the config file as a Go's text/template")
+	PreviewCmd.Flags().BoolVar(&withTemplateWithEnv, "template-with-env", false, "parse the config file as a Go's text/template with environment variables injected")
+	PreviewCmd.Flags().IntVar(&previewLimit, "limit", 20, "number of records to preview")
+	PreviewCmd.Flags().StringVar(&previewFormat, "format", "table", "output format (table or json)")
+}
+
+// PreviewCmd defines `gallon preview` command.
+var PreviewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Print the records extracted by the input plugin and the transforms",
+	Args:  cobra.ExactArgs(1),
+	// Errors are reported by the caller, so the usage is not printed for a failed preview.
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configFileBody, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		return PreviewGallon(cmd.Context(), cmd.OutOrStdout(), configFileBody, PreviewOptions{
+			AsTemplate: withTemplate || withTemplateWithEnv,
+			WithEnv:    withTemplateWithEnv,
+			Limit:      previewLimit,
+			Format:     previewFormat,
+		})
+	},
+}
+
+type PreviewOptions struct {
+	AsTemplate bool
+	WithEnv    bool
+	// Limit is the number of records to extract. The input plugin is stopped after that.
+	Limit int
+	// Format is `table` (aligned columns) or `json` (JSON lines).
+	Format string
+}
+
+// PreviewGallon extracts opts.Limit records with the input plugin and the transforms in the config yaml, and writes them to w.
+// The output plugins are not created, so nothing is written to the destinations.
+func PreviewGallon(ctx context.Context, w io.Writer, configYml []byte, opts PreviewOptions) error {
+	if opts.Limit <= 0 {
+		return errors.New("limit must be positive")
+	}
+	if opts.Format != "table" && opts.Format != "json" {
+		return fmt.Errorf("unsupported format: %v (supported: table, json)", opts.Format)
+	}
+
+	configBytes, err := renderConfig(configYml, RunGallonOptions{AsTemplate: opts.AsTemplate, WithEnv: opts.WithEnv})
+	if err != nil {
+		return err
+	}
+
+	var config gallon.GallonConfig[WithTypeConfig, yaml.Node]
+	if err := yaml.Unmarshal(configBytes, &config); err != nil {
+		return err
+	}
+
+	input, err := gallon.NewInputPluginFromConfig(config.In.Type, configBytes)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := input.Cleanup(); err != nil {
+			zap.S().Errorw("Failed to cleanup input plugin", "error", err)
+		}
+	}()
+
+	transforms := []gallon.TransformPlugin{}
+	for _, node := range config.Transforms {
+		transform, err := newTransformPlugin(&node)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := transform.Cleanup(); err != nil {
+				zap.S().Errorw("Failed to cleanup transform plugin", "error", err)
+			}
+		}()
+
+		transforms = append(transforms, transform)
+	}
+
+	output := gallon.NewOutputPluginPreview()
+
+	g := gallon.Gallon{
+		Logger:      zapr.NewLogger(zap.L()),
+		Input:       gallon.NewInputPluginLimit(input, opts.Limit),
+		Output:      output,
+		Transforms:  transforms,
+		ErrorPolicy: config.Errors,
+	}
+	result, err := g.RunWithResult(ctx)
+	if err != nil {
+		return err
+	}
+	if result.RejectedRecords > 0 {
+		zap.S().Warnw("Some records are rejected and not previewed", "rejected", result.RejectedRecords)
+	}
+
+	if opts.Format == "json" {
+		return output.RenderJSON(w)
+	}
+
+	return output.RenderTable(w)
+}
@@ -0,0 +1,429 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"github.com/myuon/gallon/gallon"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+var replayDeadLetterPath string
+
+func init() {
+	ReplayCmd.Flags().BoolVar(&withTemplate, "template", false, "parse the config file as a Go's text/template")
+	ReplayCmd.Flags().BoolVar(&withTemplateWithEnv, "template-with-env", false, "parse the config file as a Go's text/template with environment variables injected")
+	ReplayCmd.Flags().StringVar(&replayDeadLetterPath, "dead-letter", "", "write the records which fail again to the file in JSONL format")
+}
+
+// ReplayCmd defines `gallon replay` command.
+var ReplayCmd = &cobra.Command{
+	Use:          "replay <config> <deadletter.jsonl>",
+	Short:        "Load dead-lettered records with the output plugins of a config",
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ReplayGallonWithPathContext(cmd.Context(), args[0], args[1], ReplayGallonOptions{
+			RunGallonOptions: RunGallonOptions{
+				AsTemplate: withTemplate || withTemplateWithEnv,
+				WithEnv:    withTemplateWithEnv,
+			},
+			DeadLetterPath: replayDeadLetterPath,
+		})
+	},
+}
+
+type ReplayGallonOptions struct {
+	RunGallonOptions
+	// DeadLetterPath is the path of a JSONL file to write the records which fail again (optional)
+	DeadLetterPath string
+}
+
+// ReplayGallonWithPath loads the records in the dead letter file (See gallon.ReadDeadLetters) with the output plugins of the config.
+//
+// Only the records rejected in the `load` stage can be replayed, since the records rejected in the other stages have not been
+// converted by the input plugin or the transforms. The schema of the records is the one of the input plugin with the transforms applied.
+// The output plugins (`out` and `outs`) run in append mode (`append: true`), so that the destination is not truncated.
+// The `deadLetter` section of the config is not used.
+// It reports the result of each record, and returns an error if any of the records fails again.
+func ReplayGallonWithPath(configPath string, deadLetterPath string, opts ReplayGallonOptions) error {
+	return ReplayGallonWithPathContext(context.Background(), configPath, deadLetterPath, opts)
+}
+
+// ReplayGallonWithPathContext is the same as ReplayGallonWithPath, but the replay is cancelled with ctx.
+func ReplayGallonWithPathContext(ctx context.Context, configPath string, deadLetterPath string, opts ReplayGallonOptions) error {
+	configYml, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	configBytes, err := renderConfig(configYml, opts.RunGallonOptions)
+	if err != nil {
+		return err
+	}
+
+	configBytes, err = withAppendOutput(configBytes)
+	if err != nil {
+		return err
+	}
+
+	var config gallon.GallonConfig[WithTypeConfig, WithTypeConfig]
+	if err := yaml.Unmarshal(configBytes, &config); err != nil {
+		return err
+	}
+
+	deadLetterFile, err := os.Open(deadLetterPath)
+	if err != nil {
+		return err
+	}
+	defer deadLetterFile.Close()
+
+	deadLetters, err := gallon.ReadDeadLetters(deadLetterFile)
+	if err != nil {
+		return err
+	}
+
+	for i, d := range deadLetters {
+		if d.Stage != gallon.GallonStageLoad {
+			return fmt.Errorf("cannot replay the record %v rejected in the %v stage: only the records rejected in the load stage can be replayed", i+1, d.Stage)
+		}
+		if _, ok := d.Record.(*gallon.GallonRecord); !ok {
+			return fmt.Errorf("cannot replay the record %v: record is not an object: %v", i+1, d.Record)
+		}
+	}
+
+	schema, err := gallon.TransformedSchema(configBytes)
+	if err != nil {
+		return err
+	}
+
+	results := &replayResults{failures: map[int]error{}}
+
+	// `out` can be omitted if `outs` is given
+	var output gallon.OutputPlugin
+	if config.Out.Type != "" || len(config.Outs) == 0 {
+		output, err = gallon.NewOutputPluginFromConfig(config.Out.Type, configBytes)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := output.Cleanup(); err != nil {
+				zap.S().Errorw("Failed to cleanup output plugin", "error", err)
+			}
+		}()
+
+		output = &replayOutput{output: output, results: results}
+	}
+
+	outputs := []gallon.OutputPlugin{}
+	for _, node := range config.Outs {
+		output, err := newOutputPluginFromNode(&node)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := output.Cleanup(); err != nil {
+				zap.S().Errorw("Failed to cleanup output plugin", "error", err)
+			}
+		}()
+
+		outputs = append(outputs, &replayOutput{output: output, results: results})
+	}
+
+	var deadLetter gallon.OutputPlugin
+	if opts.DeadLetterPath != "" {
+		if isSameFile(opts.DeadLetterPath, deadLetterPath) {
+			return errors.New("dead letter output must be different from the replayed file: " + deadLetterPath)
+		}
+
+		deadLetterConfig, err := yaml.Marshal(map[string]any{
+			"out": map[string]any{
+				"type":     "file",
+				"format":   "jsonl",
+				"filepath": opts.DeadLetterPath,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		deadLetter, err = gallon.NewOutputPluginFileFromConfig(deadLetterConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	var logger logr.Logger
+	if opts.Logger != nil {
+		logger = *opts.Logger
+	} else {
+		logger = zapr.NewLogger(zap.L())
+	}
+
+	// every record is replayed even if some of them fail in every output
+	maxErrors := len(deadLetters) * (len(outputs) + 1)
+
+	g := gallon.Gallon{
+		Logger:          logger,
+		Input:           gallon.NewInputPluginDeadLetter(deadLetters, 1000, schema),
+		Output:          output,
+		Outputs:         outputs,
+		OnOutputFailure: config.OnOutputFailure,
+		ErrorPolicy:     gallon.ErrorPolicy{Max: &maxErrors},
+		DeadLetter:      deadLetter,
+	}
+
+	if _, err := g.RunWithResult(ctx); err != nil {
+		return err
+	}
+
+	failures := results.failures
+	unmatched := results.unmatched
+
+	for i, d := range deadLetters {
+		if err, ok := failures[i]; ok {
+			logger.Error(err, "replay failed", "index", i+1, "stage", d.Stage)
+			continue
+		}
+
+		logger.Info("replay succeeded", "index", i+1, "stage", d.Stage)
+	}
+
+	failed := min(len(failures)+unmatched, len(deadLetters))
+	logger.Info("replay summary", "succeeded", len(deadLetters)-failed, "failed", failed)
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v records failed to replay", failed, len(deadLetters))
+	}
+
+	return nil
+}
+
+// replayResults collects the errors of the replayed records from all the outputs.
+type replayResults struct {
+	mu sync.Mutex
+	// failures is the first error of each record, by the index in the dead letters
+	failures map[int]error
+	// unmatched is the number of the errors which are not matched with any record
+	unmatched int
+}
+
+// replayOutput passes the replayed records to output, and matches the records rejected by it with their indices in the dead letters.
+// The records reach every output in the order of the dead letters, since a replay has no transforms, so the index of a record is
+// its sequence number in the output. A rejected record is matched by its JSON, since the output may report a copy of it
+// (e.g. one rebuilt by the command of the exec plugin).
+type replayOutput struct {
+	output  gallon.OutputPlugin
+	results *replayResults
+	logger  logr.Logger
+
+	mu sync.Mutex
+	// sent is the records sent to output, in the order of the dead letters
+	sent []gallon.GallonRecord
+	// sentJSON caches the JSON of the records in sent (See replayRecordJSON)
+	sentJSON map[int]string
+	failed   map[int]bool
+}
+
+var _ gallon.OutputPlugin = &replayOutput{}
+var _ gallon.SchemaOutputPlugin = &replayOutput{}
+
+func (p *replayOutput) ReplaceLogger(logger logr.Logger) {
+	p.logger = logger
+	p.output.ReplaceLogger(logger)
+}
+
+func (p *replayOutput) Cleanup() error {
+	return nil
+}
+
+func (p *replayOutput) SetSchema(schema gallon.GallonSchema) {
+	if output, ok := p.output.(gallon.SchemaOutputPlugin); ok {
+		output.SetSchema(schema)
+	}
+}
+
+func (p *replayOutput) Load(
+	ctx context.Context,
+	messages chan []gallon.GallonRecord,
+	errs chan error,
+) error {
+	p.sentJSON = map[int]string{}
+	p.failed = map[int]bool{}
+
+	received := make(chan []gallon.GallonRecord)
+	outputErrs := make(chan error)
+	loadDone := make(chan struct{})
+
+	go func() {
+		defer close(received)
+
+		for msgs := range messages {
+			p.mu.Lock()
+			p.sent = append(p.sent, msgs...)
+			p.mu.Unlock()
+
+			select {
+			case received <- msgs:
+			case <-loadDone:
+				return
+			}
+		}
+	}()
+
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+
+		for err := range outputErrs {
+			p.fail(err)
+			errs <- err
+		}
+	}()
+
+	err := p.output.Load(ctx, received, outputErrs)
+	close(loadDone)
+	close(outputErrs)
+	<-forwarded
+
+	return err
+}
+
+// fail records the error for the first record sent to the output which has the same JSON as the rejected record, and has not failed yet.
+func (p *replayOutput) fail(err error) {
+	index := -1
+
+	var recordErr *gallon.RecordError
+	if errors.As(err, &recordErr) {
+		if rejected, ok := replayRecordJSON(recordErr.Record); ok {
+			p.mu.Lock()
+			for i, record := range p.sent {
+				if p.failed[i] {
+					continue
+				}
+
+				sent, ok := p.sentJSON[i]
+				if !ok {
+					sent, _ = replayRecordJSON(&record)
+					p.sentJSON[i] = sent
+				}
+
+				if sent == rejected {
+					p.failed[i] = true
+					index = i
+					break
+				}
+			}
+			p.mu.Unlock()
+		}
+	}
+
+	p.results.mu.Lock()
+	defer p.results.mu.Unlock()
+
+	if index < 0 {
+		p.results.unmatched++
+		p.logger.Error(err, "replay failed")
+		return
+	}
+
+	// the record may have already failed in another output
+	if _, ok := p.results.failures[index]; !ok {
+		p.results.failures[index] = err
+	}
+}
+
+// replayRecordJSON returns the JSON of the record with the keys sorted, to compare the records regardless of the order of the keys.
+func replayRecordJSON(record any) (string, bool) {
+	bs, err := json.Marshal(record)
+	if err != nil {
+		return "", false
+	}
+
+	var value any
+	if err := json.Unmarshal(bs, &value); err != nil {
+		return "", false
+	}
+
+	bs, err = json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+
+	return string(bs), true
+}
+
+// withAppendOutput sets `append: true` to the `out` and `deadLetter` sections and each element of the `outs` section of the config.
+func withAppendOutput(configYml []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(configYml, &root); err != nil {
+		return nil, err
+	}
+
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("config must be a mapping")
+	}
+
+	document := root.Content[0]
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		switch document.Content[i].Value {
+		case "out", "deadLetter":
+			if err := setAppend(document.Content[i+1]); err != nil {
+				return nil, fmt.Errorf("%v %w", document.Content[i].Value, err)
+			}
+		case "outs":
+			outs := document.Content[i+1]
+			if outs.Kind != yaml.SequenceNode {
+				return nil, errors.New("outs must be a sequence")
+			}
+
+			for _, out := range outs.Content {
+				if err := setAppend(out); err != nil {
+					return nil, fmt.Errorf("outs %w", err)
+				}
+			}
+		}
+	}
+
+	return yaml.Marshal(&root)
+}
+
+func setAppend(out *yaml.Node) error {
+	if out.Kind != yaml.MappingNode {
+		return errors.New("must be a mapping")
+	}
+
+	appendValue := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"}
+
+	for j := 0; j+1 < len(out.Content); j += 2 {
+		if out.Content[j].Value == "append" {
+			out.Content[j+1] = appendValue
+			return nil
+		}
+	}
+
+	out.Content = append(out.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "append"}, appendValue)
+
+	return nil
+}
+
+func isSameFile(a string, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+
+	return absA == absB
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_replay_results(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yml")
+	deadLetterPath := filepath.Join(dir, "dead_letter.jsonl")
+
+	// the command rebuilds the rejected records, and the empty records are identical
+	config := `
+in:
+  type: random
+  schema:
+    name:
+      type: string
+out:
+  type: exec
+  command: sh
+  args:
+    - -c
+    - |
+      cat > /dev/null
+      echo '{"type":"error","message":"rejected","record":{"name":"b"}}'
+      echo '{"type":"error","message":"rejected","record":{}}'
+`
+	deadLetters := strings.Join([]string{
+		`{"record":{"name":"a"},"error":"failed","stage":"load","timestamp":"2024-01-01T00:00:00Z"}`,
+		`{"record":{},"error":"failed","stage":"load","timestamp":"2024-01-01T00:00:00Z"}`,
+		`{"record":{"name":"b"},"error":"failed","stage":"load","timestamp":"2024-01-01T00:00:00Z"}`,
+		`{"record":{},"error":"failed","stage":"load","timestamp":"2024-01-01T00:00:00Z"}`,
+	}, "\n") + "\n"
+
+	for path, body := range map[string]string{configPath: config, deadLetterPath: deadLetters} {
+		if err := os.WriteFile(path, []byte(body), 0666); err != nil {
+			t.Fatalf("Could not write file: %s", err)
+		}
+	}
+
+	var mu sync.Mutex
+	var lines []string
+	logger := funcr.New(func(prefix, args string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if strings.Contains(args, `"msg"="replay`) {
+			lines = append(lines, args)
+		}
+	}, funcr.Options{})
+
+	err := ReplayGallonWithPathContext(context.Background(), configPath, deadLetterPath, ReplayGallonOptions{
+		RunGallonOptions: RunGallonOptions{Logger: &logger},
+	})
+	assert.EqualError(t, err, "2 of 4 records failed to replay")
+
+	results := []string{}
+	for _, line := range lines {
+		switch {
+		case strings.Contains(line, `"msg"="replay succeeded"`):
+			results = append(results, "succeeded "+logValue(line, "index"))
+		case strings.Contains(line, `"msg"="replay failed"`):
+			results = append(results, "failed "+logValue(line, "index"))
+		}
+	}
+	assert.Equal(t, []string{"succeeded 1", "failed 2", "failed 3", "succeeded 4"}, results)
+}
+
+// logValue returns the value of the key in a line of funcr, or an empty string if there is no such key.
+func logValue(line string, key string) string {
+	_, value, ok := strings.Cut(line, `"`+key+`"=`)
+	if !ok {
+		return ""
+	}
+
+	value, _, _ = strings.Cut(value, " ")
+	return value
+}
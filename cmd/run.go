@@ -5,10 +5,16 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"text/tabwriter"
 	"text/template"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
@@ -20,10 +26,24 @@ import (
 
 var withTemplate bool
 var withTemplateWithEnv bool
+var withResume bool
+var metricsAddr string
+var traceExporter string
+var traceFile string
+var withDryRun bool
+var sampleRecords int
+var parallelFiles int
 
 func init() {
 	RunCmd.Flags().BoolVar(&withTemplate, "template", false, "parse the config file as a Go's text/template")
 	RunCmd.Flags().BoolVar(&withTemplateWithEnv, "template-with-env", false, "parse the config file as a Go's text/template with environment variables injected")
+	RunCmd.Flags().BoolVar(&withResume, "resume", false, "resume the migration from the last checkpoint (requires the checkpoint section)")
+	RunCmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "serve Prometheus metrics at the address (e.g. :9090) during the migration")
+	RunCmd.Flags().StringVar(&traceExporter, "trace-exporter", "", "export OpenTelemetry traces with the exporter (otlp, stdout or file)")
+	RunCmd.Flags().StringVar(&traceFile, "trace-file", "", "path of the file for --trace-exporter file")
+	RunCmd.Flags().BoolVar(&withDryRun, "dry-run", false, "validate the records with the transforms and the output plugins without writing anything")
+	RunCmd.Flags().IntVar(&sampleRecords, "sample", 100, "number of records to extract with --dry-run (0 for all the records)")
+	RunCmd.Flags().IntVar(&parallelFiles, "parallel", 1, "number of config files run at the same time when the glob pattern matches multiple files")
 }
 
 // RunCmd defines `gallon run` command.
@@ -31,16 +51,43 @@ var RunCmd = &cobra.Command{
 	Use:   "run",
 	Short: "Run a migration",
 	Args:  cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	// Errors are reported by the caller, so the usage is not printed for a failed migration.
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		configPath := args[0]
 
-		if err := RunGallonWithPath(configPath, RunGallonOptions{
+		if metricsAddr != "" {
+			stop, err := serveMetrics(metricsAddr)
+			if err != nil {
+				return err
+			}
+			defer stop()
+		}
+
+		if traceExporter != "" {
+			flush, err := setupTracing(cmd.Context(), traceExporter, traceFile)
+			if err != nil {
+				return err
+			}
+			defer flush()
+		}
+
+		results, err := RunGallonWithPathResults(cmd.Context(), configPath, RunGallonOptions{
 			AsTemplate: withTemplate || withTemplateWithEnv,
 			WithEnv:    withTemplateWithEnv,
-		}); err != nil {
-			zap.S().Error(err)
-			return
+			Resume:     withResume,
+			DryRun:     withDryRun,
+			Sample:     sampleRecords,
+			Parallel:   parallelFiles,
+		})
+		// the summary is printed for a glob pattern even if it matches a single file, so that the output does not depend on the matches
+		if isGlobPattern(configPath) || cmd.Flags().Changed("parallel") {
+			if err := WriteRunSummary(cmd.OutOrStdout(), results); err != nil {
+				return err
+			}
 		}
+
+		return err
 	},
 }
 
@@ -50,36 +97,175 @@ type WithTypeConfig struct {
 
 // RunGallonWithPath runs a migration with the given config file path.
 // You can use glob pattern to run multiple config files.
+//
+// All matched files are run even if some of them fail, and the errors of the failed files are joined and returned.
 func RunGallonWithPath(configPath string, opts RunGallonOptions) error {
+	return RunGallonWithPathContext(context.Background(), configPath, opts)
+}
+
+// RunGallonWithPathContext is the same as RunGallonWithPath, but the migrations are cancelled with ctx.
+// The files which are not started before the cancellation are skipped.
+func RunGallonWithPathContext(ctx context.Context, configPath string, opts RunGallonOptions) error {
+	_, err := RunGallonWithPathResults(ctx, configPath, opts)
+	return err
+}
+
+// RunFileResult is the result of a config file run by RunGallonWithPathResults.
+type RunFileResult struct {
+	Path string
+	// Err is nil if the migration succeeded.
+	Err error
+	// Skipped is true if the file was not started because of the cancellation.
+	Skipped  bool
+	Duration time.Duration
+}
+
+// Status returns `ok`, `failed` or `skipped`.
+func (r RunFileResult) Status() string {
+	switch {
+	case r.Skipped:
+		return "skipped"
+	case r.Err != nil:
+		return "failed"
+	default:
+		return "ok"
+	}
+}
+
+// RunGallonWithPathResults is the same as RunGallonWithPathContext, but also returns the result of each matched file in the order of the files.
+//
+// opts.Parallel files are run at the same time. The path of the file is added to the values of the logger, so that the logs of the files can be told apart.
+func RunGallonWithPathResults(ctx context.Context, configPath string, opts RunGallonOptions) ([]RunFileResult, error) {
+	if opts.Parallel < 0 {
+		return nil, errors.New("parallel must not be negative")
+	}
+
 	files, err := filepath.Glob(configPath)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	sugaredLogger(defaultLogger(opts)).Infow("Detected config files", "files", files, "parallel", max(opts.Parallel, 1))
+
+	results := make([]RunFileResult, len(files))
+	runParallel(len(files), max(opts.Parallel, 1), func(i int) {
+		results[i] = runGallonFile(ctx, files[i], opts)
+	})
+
+	var failures []error
+	for _, result := range results {
+		if result.Err != nil {
+			failures = append(failures, fmt.Errorf("%v: %w", result.Path, result.Err))
+		}
 	}
 
-	zap.S().Infow("Detected config files", "files", files)
+	return results, errors.Join(failures...)
+}
 
-	for _, file := range files {
-		zap.S().Infow("RunGallon", "path", file)
+// runParallel calls run for 0 to n-1 in order, with at most parallel calls running at the same time.
+func runParallel(n int, parallel int, run func(i int)) {
+	jobs := make(chan int)
 
-		configFileBody, err := os.ReadFile(file)
-		if err != nil {
-			zap.S().Errorw("Failed to read config file", "path", file, "error", err)
-			continue
+	var wg sync.WaitGroup
+	for range min(parallel, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				run(i)
+			}
+		}()
+	}
+
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// isGlobPattern reports whether the path has the special characters of filepath.Match.
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// runGallonFile runs a migration with the config file, logging with the path of the file.
+func runGallonFile(ctx context.Context, file string, opts RunGallonOptions) RunFileResult {
+	logger := defaultLogger(opts).WithValues("path", file)
+	opts.Logger = &logger
+	log := sugaredLogger(logger)
+
+	if err := ctx.Err(); err != nil {
+		log.Errorw("Skipped", "error", err)
+		return RunFileResult{Path: file, Err: err, Skipped: true}
+	}
+
+	log.Infow("RunGallon")
+	start := time.Now()
+
+	configFileBody, err := os.ReadFile(file)
+	if err != nil {
+		log.Errorw("Failed to read config file", "error", err)
+		return RunFileResult{Path: file, Err: err, Duration: time.Since(start)}
+	}
+
+	if err := RunGallonWithContext(ctx, configFileBody, opts); err != nil {
+		log.Errorw("Failed to run gallon", "error", err)
+		return RunFileResult{Path: file, Err: err, Duration: time.Since(start)}
+	}
+
+	return RunFileResult{Path: file, Duration: time.Since(start)}
+}
+
+// WriteRunSummary writes the results of the files as a table, followed by the number of the passed and the failed files.
+func WriteRunSummary(w io.Writer, results []RunFileResult) error {
+	var table bytes.Buffer
+	tw := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSTATUS\tDURATION\tERROR")
+
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status()]++
+
+		message := ""
+		if result.Err != nil {
+			// joined errors are kept in a line of the table
+			message = strings.ReplaceAll(result.Err.Error(), "\n", "; ")
 		}
 
-		if err := RunGallonWithOptions(configFileBody, opts); err != nil {
-			zap.S().Errorw("Failed to run gallon", "path", file, "error", err)
-			continue
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", result.Path, result.Status(), result.Duration.Round(time.Millisecond), message)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// the padding of the empty errors is trimmed
+	for _, line := range strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n") {
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
 		}
 	}
 
-	return nil
+	_, err := fmt.Fprintf(w, "(%v passed, %v failed, %v skipped)\n", counts["ok"], counts["failed"], counts["skipped"])
+	return err
 }
 
 type RunGallonOptions struct {
 	AsTemplate bool
 	WithEnv    bool
-	Logger     *logr.Logger
+	// Resume restarts the migration from the checkpoint saved by the `checkpoint` section.
+	// The outputs are run with `append: true` if there is a checkpoint.
+	Resume bool
+	// DryRun extracts Sample records (all the records if Sample is 0) and validates them with the transforms and the outputs
+	// (See gallon.ValidatingOutputPlugin), without writing to the outputs, the dead letter or the checkpoint.
+	// It fails if any record is rejected, or if the input and output schemas have type mismatches (See gallon.CheckSchemaCompatibility).
+	DryRun bool
+	Sample int
+	// Parallel is the number of config files run at the same time by RunGallonWithPath (default: 1).
+	Parallel int
+	Logger   *logr.Logger
 }
 
 // RunGallon runs a migration with the given config yaml.
@@ -92,13 +278,13 @@ func RunGallon(configYml []byte) error {
 	})
 }
 
-// RunGallonWithOptions runs a migration with the given config yaml. See GallonConfig for the schema of the file.
-func RunGallonWithOptions(configYml []byte, opts RunGallonOptions) error {
+// renderConfig renders the config yaml as a Go's text/template if AsTemplate is specified.
+func renderConfig(configYml []byte, opts RunGallonOptions) ([]byte, error) {
 	configBytes := configYml
 	if opts.AsTemplate {
 		tmpl, err := template.New("gallonConfig").Parse(string(configYml))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		dataMap := map[string]string{}
@@ -113,79 +299,314 @@ func RunGallonWithOptions(configYml []byte, opts RunGallonOptions) error {
 
 		var buf bytes.Buffer
 		if err := tmpl.Execute(&buf, dataMap); err != nil {
-			return err
+			return nil, err
 		}
 
 		configBytes = buf.Bytes()
 	}
 
+	return configBytes, nil
+}
+
+// RunGallonWithOptions runs a migration with the given config yaml. See GallonConfig for the schema of the file.
+func RunGallonWithOptions(configYml []byte, opts RunGallonOptions) error {
+	return RunGallonWithContext(context.Background(), configYml, opts)
+}
+
+// RunGallonWithContext is the same as RunGallonWithOptions, but the migration is cancelled with ctx.
+// On cancellation, the plugins stop and clean up (e.g. the temporary tables) before it returns.
+func RunGallonWithContext(ctx context.Context, configYml []byte, opts RunGallonOptions) error {
+	logger := defaultLogger(opts)
+	log := sugaredLogger(logger)
+
+	configBytes, err := renderConfig(configYml, opts)
+	if err != nil {
+		return err
+	}
+
 	var config gallon.GallonConfig[WithTypeConfig, WithTypeConfig]
 
 	if err := yaml.Unmarshal(configBytes, &config); err != nil {
 		return err
 	}
 
-	input, err := findInputPlugin(config.In.Type, configBytes)
+	if opts.DryRun && opts.Resume {
+		return errors.New("dry run cannot be resumed")
+	}
+
+	var checkpointStore gallon.CheckpointStore
+	if config.Checkpoint != nil && !opts.DryRun {
+		checkpointStore, err = gallon.NewCheckpointStoreFromConfig(*config.Checkpoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	var checkpoint *gallon.Checkpoint
+	if opts.Resume {
+		if checkpointStore == nil {
+			return errors.New("checkpoint section is required to resume")
+		}
+
+		checkpoint, err = checkpointStore.Load(ctx)
+		if err != nil {
+			return err
+		}
+
+		if checkpoint == nil {
+			log.Infow("No checkpoint found, starting from the beginning")
+		} else {
+			log.Infow("Resume from checkpoint", "batches", checkpoint.Batches, "updatedAt", checkpoint.UpdatedAt)
+
+			// the records loaded before the checkpoint must be kept
+			configBytes, err = withAppendOutput(configBytes)
+			if err != nil {
+				return err
+			}
+
+			config = gallon.GallonConfig[WithTypeConfig, WithTypeConfig]{}
+			if err := yaml.Unmarshal(configBytes, &config); err != nil {
+				return err
+			}
+		}
+	}
+
+	// the schemas are checked before any data moves
+	schemaIssues, err := gallon.CheckSchemaCompatibility(configBytes)
+	if err != nil {
+		return err
+	}
+	schemaMismatches := 0
+	for _, issue := range schemaIssues {
+		log.Warnw("Schema issue", "output", issue.Output, "column", issue.Column, "kind", issue.Kind, "message", issue.Message)
+
+		if issue.Kind == gallon.SchemaIssueTypeMismatch {
+			schemaMismatches++
+		}
+	}
+
+	input, err := gallon.NewInputPluginFromConfig(config.In.Type, configBytes)
 	if err != nil {
 		return err
 	}
 
 	defer func() {
 		if err := input.Cleanup(); err != nil {
-			zap.S().Errorw("Failed to cleanup input plugin", "error", err)
+			log.Errorw("Failed to cleanup input plugin", "error", err)
 		}
 	}()
 
-	output, err := findOutputPlugin(config.Out.Type, configBytes)
-	if err != nil {
+	if checkpoint != nil {
+		resumable, ok := input.(gallon.ResumableInputPlugin)
+		if !ok {
+			return fmt.Errorf("input plugin does not support resume: %v", config.In.Type)
+		}
+
+		if err := resumable.Resume(checkpoint.Cursor); err != nil {
+			return err
+		}
+	}
+
+	if opts.DryRun && opts.Sample > 0 {
+		input = gallon.NewInputPluginLimit(input, opts.Sample)
+	}
+
+	// `out` can be omitted if `outs` is given
+	var output gallon.OutputPlugin
+	if config.Out.Type != "" || len(config.Outs) == 0 {
+		output, err = gallon.NewOutputPluginFromConfig(config.Out.Type, configBytes)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := output.Cleanup(); err != nil {
+				log.Errorw("Failed to cleanup output plugin", "error", err)
+			}
+		}()
+
+		if opts.DryRun {
+			output = gallon.NewOutputPluginDryRun(output)
+		}
+	}
+
+	outputs := []gallon.OutputPlugin{}
+	for _, node := range config.Outs {
+		output, err := newOutputPluginFromNode(&node)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := output.Cleanup(); err != nil {
+				log.Errorw("Failed to cleanup output plugin", "error", err)
+			}
+		}()
+
+		if opts.DryRun {
+			output = gallon.NewOutputPluginDryRun(output)
+		}
+
+		outputs = append(outputs, output)
+	}
+
+	if err := config.OnOutputFailure.Validate(); err != nil {
 		return err
 	}
 
-	defer func() {
-		if err := output.Cleanup(); err != nil {
-			zap.S().Errorw("Failed to cleanup output plugin", "error", err)
+	if err := config.RateLimit.Validate(); err != nil {
+		return err
+	}
+
+	if err := config.Batch.Validate(); err != nil {
+		return err
+	}
+
+	verify := config.Verify
+	if verify != nil {
+		if err := verify.Validate(); err != nil {
+			return err
 		}
-	}()
+	}
+	if opts.DryRun {
+		// nothing is written to be verified
+		verify = nil
+	}
 
-	var logger logr.Logger
-	if opts.Logger != nil {
-		logger = *opts.Logger
-	} else {
-		logger = zapr.NewLogger(zap.L())
+	transforms := []gallon.TransformPlugin{}
+	for _, node := range config.Transforms {
+		transform, err := newTransformPlugin(&node)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := transform.Cleanup(); err != nil {
+				log.Errorw("Failed to cleanup transform plugin", "error", err)
+			}
+		}()
+
+		transforms = append(transforms, transform)
+	}
+
+	var deadLetter gallon.OutputPlugin
+	if !config.DeadLetter.IsZero() && !opts.DryRun {
+		deadLetter, err = newOutputPluginFromNode(&config.DeadLetter)
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := deadLetter.Cleanup(); err != nil {
+				log.Errorw("Failed to cleanup dead letter plugin", "error", err)
+			}
+		}()
+	}
+
+	errorPolicy := config.Errors
+	if opts.DryRun {
+		// every invalid record is reported
+		maxErrors := math.MaxInt
+		errorPolicy = gallon.ErrorPolicy{Max: &maxErrors}
 	}
 
 	g := gallon.Gallon{
-		Logger: logger,
-		Input:  input,
-		Output: output,
+		Logger:          logger,
+		Input:           input,
+		Output:          output,
+		Outputs:         outputs,
+		OnOutputFailure: config.OnOutputFailure,
+		Transforms:      transforms,
+		ErrorPolicy:     errorPolicy,
+		DeadLetter:      deadLetter,
+		Checkpoint:      checkpointStore,
+		RateLimit:       config.RateLimit,
+		Batch:           config.Batch,
+		Verify:          verify,
+	}
+	result, err := g.RunWithResult(ctx)
+	logger.Info(
+		"migration summary",
+		"extracted", result.ExtractedRecords,
+		"loaded", result.LoadedRecords,
+		"rejected", result.RejectedRecords,
+		"batches", result.ExtractedBatches,
+		"extractDuration", result.ExtractDuration.String(),
+		"loadDuration", result.LoadDuration.String(),
+		"duration", result.Duration.String(),
+	)
+	if len(result.Outputs) > 1 {
+		for i, r := range result.Outputs {
+			logger.Info(
+				"output summary",
+				"output", i,
+				"loaded", r.LoadedRecords,
+				"rejected", r.RejectedRecords,
+				"duration", r.Duration.String(),
+				"error", r.Err,
+			)
+		}
 	}
-	if err := g.Run(context.Background()); err != nil {
+	if err != nil {
 		return err
 	}
 
+	if opts.DryRun && result.RejectedRecords > 0 {
+		return fmt.Errorf("dry run found %v invalid records", result.RejectedRecords)
+	}
+	if opts.DryRun && schemaMismatches > 0 {
+		return fmt.Errorf("dry run found %v type mismatches between the input and output schemas", schemaMismatches)
+	}
+
 	return nil
 }
 
-func findInputPlugin(t string, configYml []byte) (gallon.InputPlugin, error) {
-	if t == "dynamodb" {
-		return gallon.NewInputPluginDynamoDbFromConfig(configYml)
-	} else if t == "sql" {
-		return gallon.NewInputPluginSqlFromConfig(configYml)
-	} else if t == "random" {
-		return gallon.NewInputPluginRandomFromConfig(configYml)
+// defaultLogger returns opts.Logger, or the global zap logger if it is not given.
+func defaultLogger(opts RunGallonOptions) logr.Logger {
+	if opts.Logger != nil {
+		return *opts.Logger
+	}
+
+	return zapr.NewLogger(zap.L())
+}
+
+// sugaredLogger returns the zap logger under logger, so that the logs of the cli have the same values (e.g. the path of the config file) as the ones of the plugins.
+// The global zap logger is returned if logger is not backed by zap.
+func sugaredLogger(logger logr.Logger) *zap.SugaredLogger {
+	if underlier, ok := logger.GetSink().(zapr.Underlier); ok {
+		return underlier.GetUnderlying().Sugar()
 	}
 
-	return nil, errors.New("plugin not found: " + t)
+	return zap.S()
 }
 
-func findOutputPlugin(t string, configYml []byte) (gallon.OutputPlugin, error) {
-	if t == "bigquery" {
-		return gallon.NewOutputPluginBigQueryFromConfig(configYml)
-	} else if t == "file" {
-		return gallon.NewOutputPluginFileFromConfig(configYml)
-	} else if t == "stdout" {
-		return gallon.NewOutputPluginStdoutFromConfig(configYml)
+// newOutputPluginFromNode creates an output plugin from a section other than `out` (an element of `outs`, or `deadLetter`).
+// Since output plugins read their config from `out`, the section is passed as `out`.
+func newOutputPluginFromNode(node *yaml.Node) (gallon.OutputPlugin, error) {
+	var config WithTypeConfig
+	if err := node.Decode(&config); err != nil {
+		return nil, err
+	}
+
+	configYml, err := yaml.Marshal(map[string]*yaml.Node{"out": node})
+	if err != nil {
+		return nil, err
+	}
+
+	return gallon.NewOutputPluginFromConfig(config.Type, configYml)
+}
+
+// newTransformPlugin creates a transform plugin from an element of the `transforms` section.
+func newTransformPlugin(node *yaml.Node) (gallon.TransformPlugin, error) {
+	var config WithTypeConfig
+	if err := node.Decode(&config); err != nil {
+		return nil, err
+	}
+
+	configYml, err := yaml.Marshal(node)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, errors.New("plugin not found: " + t)
+	return gallon.NewTransformPluginFromConfig(config.Type, configYml)
 }
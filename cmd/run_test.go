@@ -0,0 +1,329 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/myuon/gallon/gallon"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	gallon.RegisterInputPlugin("test_resumable", func(configYml []byte) (gallon.InputPlugin, error) {
+		return &inputPluginResumable{pages: 3}, nil
+	})
+}
+
+// inputPluginResumable extracts a record `{"id": <page>}` for each page, and rejects the record of the last page.
+type inputPluginResumable struct {
+	pages         int
+	startPage     int
+	cursorHandler func(cursor json.RawMessage)
+}
+
+var _ gallon.ResumableInputPlugin = &inputPluginResumable{}
+
+func (p *inputPluginResumable) Resume(cursor json.RawMessage) error {
+	return json.Unmarshal(cursor, &p.startPage)
+}
+
+func (p *inputPluginResumable) SetCursorHandler(handler func(cursor json.RawMessage)) {
+	p.cursorHandler = handler
+}
+
+func (p *inputPluginResumable) ReplaceLogger(logger logr.Logger) {
+}
+
+func (p *inputPluginResumable) Cleanup() error {
+	return nil
+}
+
+func (p *inputPluginResumable) Extract(ctx context.Context, messages chan []gallon.GallonRecord, errs chan error) error {
+	for page := p.startPage; page < p.pages; page++ {
+		record := gallon.NewGallonRecord()
+		record.Set("id", page)
+
+		if page == p.pages-1 {
+			errs <- gallon.NewRecordError(&record, errors.New("rejected"))
+			continue
+		}
+
+		if p.cursorHandler != nil {
+			p.cursorHandler(json.RawMessage(fmt.Sprintf("%v", page+1)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case messages <- []gallon.GallonRecord{record}:
+		}
+	}
+
+	return nil
+}
+
+// writeRunConfigs writes the config files of random to file migrations to dir. The config named `broken` has an unknown input plugin.
+func writeRunConfigs(t *testing.T, dir string, names ...string) {
+	for _, name := range names {
+		config := fmt.Sprintf(`
+in:
+  type: random
+  pageSize: 10
+  pageLimit: 2
+  schema:
+    id:
+      type: int
+out:
+  type: file
+  format: jsonl
+  filepath: %v
+`, filepath.Join(dir, name+".jsonl"))
+		if name == "broken" {
+			config = "in:\n  type: unknown\nout:\n  type: stdout\n"
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, name+".yml"), []byte(config), 0666); err != nil {
+			t.Fatalf("Could not write config: %s", err)
+		}
+	}
+}
+
+func Test_run_parallel(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        int
+		parallel int
+	}{
+		{name: "sequential", n: 5, parallel: 1},
+		{name: "parallel", n: 10, parallel: 3},
+		{name: "more workers than jobs", n: 2, parallel: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := map[int]int{}
+			running, maxRunning := 0, 0
+
+			// the first calls wait for each other, which fails if they are not run at the same time
+			workers := min(tt.n, tt.parallel)
+			var barrier sync.WaitGroup
+			barrier.Add(workers)
+
+			runParallel(tt.n, tt.parallel, func(i int) {
+				mu.Lock()
+				calls[i]++
+				running++
+				maxRunning = max(maxRunning, running)
+				mu.Unlock()
+
+				if i < workers {
+					barrier.Done()
+
+					waited := make(chan struct{})
+					go func() {
+						barrier.Wait()
+						close(waited)
+					}()
+
+					select {
+					case <-waited:
+					case <-time.After(5 * time.Second):
+						t.Errorf("call %v is not run in parallel", i)
+					}
+				}
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+			})
+
+			assert.Len(t, calls, tt.n)
+			for i := range tt.n {
+				assert.Equal(t, 1, calls[i], i)
+			}
+			assert.Equal(t, workers, maxRunning)
+		})
+	}
+}
+
+func Test_run_gallon_with_path_results(t *testing.T) {
+	dir := t.TempDir()
+	writeRunConfigs(t, dir, "a", "b", "broken", "c")
+
+	results, err := RunGallonWithPathResults(context.Background(), filepath.Join(dir, "*.yml"), RunGallonOptions{Parallel: 2})
+
+	var statuses []string
+	for _, result := range results {
+		statuses = append(statuses, filepath.Base(result.Path)+":"+result.Status())
+	}
+	assert.Equal(t, []string{"a.yml:ok", "b.yml:ok", "broken.yml:failed", "c.yml:ok"}, statuses)
+
+	assert.ErrorContains(t, err, filepath.Join(dir, "broken.yml")+": plugin not found: unknown")
+	assert.NotContains(t, err.Error(), "a.yml")
+
+	for _, name := range []string{"a", "b", "c"} {
+		body, err := os.ReadFile(filepath.Join(dir, name+".jsonl"))
+		assert.NoError(t, err)
+		assert.Equal(t, 20, strings.Count(string(body), "\n"), name)
+	}
+}
+
+func Test_run_gallon_with_path_results_cancelled(t *testing.T) {
+	dir := t.TempDir()
+	writeRunConfigs(t, dir, "a", "b")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := RunGallonWithPathResults(ctx, filepath.Join(dir, "*.yml"), RunGallonOptions{Parallel: 2})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	for _, result := range results {
+		assert.Equal(t, "skipped", result.Status())
+	}
+	assert.Len(t, results, 2)
+}
+
+func Test_run_gallon_with_path_results_negative_parallel(t *testing.T) {
+	_, err := RunGallonWithPathResults(context.Background(), "*.yml", RunGallonOptions{Parallel: -1})
+	assert.EqualError(t, err, "parallel must not be negative")
+}
+
+func Test_write_run_summary(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := WriteRunSummary(buf, []RunFileResult{
+		{Path: "configs/users.yml", Duration: 1234567 * time.Microsecond},
+		{Path: "configs/orders.yml", Err: errors.Join(errors.New("first"), errors.New("second")), Duration: 3 * time.Second},
+		{Path: "configs/items.yml", Err: context.Canceled, Skipped: true},
+	})
+	assert.NoError(t, err)
+
+	expected := `FILE                STATUS   DURATION  ERROR
+configs/users.yml   ok       1.235s
+configs/orders.yml  failed   3s        first; second
+configs/items.yml   skipped  0s        context canceled
+(1 passed, 1 failed, 1 skipped)
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func Test_run_cmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		configs  []string
+		pattern  string
+		wantErr  bool
+		expected []string
+	}{
+		{
+			name:     "a glob matching a file",
+			configs:  []string{"a"},
+			pattern:  "*.yml",
+			expected: []string{"a.yml  ok", "(1 passed, 0 failed, 0 skipped)"},
+		},
+		{
+			name:     "a glob with a failed file",
+			configs:  []string{"a", "broken"},
+			pattern:  "*.yml",
+			wantErr:  true,
+			expected: []string{"a.yml       ok", "broken.yml  failed", "(1 passed, 1 failed, 0 skipped)"},
+		},
+		{
+			name:    "a file",
+			configs: []string{"a"},
+			pattern: "a.yml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeRunConfigs(t, dir, tt.configs...)
+
+			buf := new(bytes.Buffer)
+			RunCmd.SetOut(buf)
+			RunCmd.SetContext(context.Background())
+			t.Cleanup(func() {
+				RunCmd.SetOut(nil)
+			})
+
+			err := RunCmd.RunE(RunCmd, []string{filepath.Join(dir, tt.pattern)})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if len(tt.expected) == 0 {
+				assert.Empty(t, buf.String())
+			}
+			for _, line := range tt.expected {
+				assert.Contains(t, buf.String(), line)
+			}
+		})
+	}
+}
+
+func Test_run_resume(t *testing.T) {
+	dir := t.TempDir()
+	outPath := filepath.Join(dir, "out.jsonl")
+	deadLetterPath := filepath.Join(dir, "dead_letter.jsonl")
+	checkpointPath := filepath.Join(dir, "checkpoint.json")
+
+	// the first page was loaded and a record was rejected before the migration was interrupted
+	files := map[string]string{
+		outPath:        "{\"id\":0}\n",
+		deadLetterPath: "{\"record\":{\"id\":-1},\"error\":\"rejected\",\"stage\":\"extract\"}\n",
+		checkpointPath: `{"cursor":1,"batches":1,"updatedAt":"2024-01-01T00:00:00Z"}`,
+	}
+	for path, body := range files {
+		if err := os.WriteFile(path, []byte(body), 0666); err != nil {
+			t.Fatalf("Could not write file: %s", err)
+		}
+	}
+
+	config := fmt.Sprintf(`
+in:
+  type: test_resumable
+out:
+  type: file
+  format: jsonl
+  filepath: %v
+deadLetter:
+  type: file
+  format: jsonl
+  filepath: %v
+checkpoint:
+  type: file
+  path: %v
+`, outPath, deadLetterPath, checkpointPath)
+
+	err := RunGallonWithContext(context.Background(), []byte(config), RunGallonOptions{Resume: true})
+	assert.NoError(t, err)
+
+	body, err := os.ReadFile(outPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"id\":0}\n{\"id\":1}\n", string(body))
+
+	body, err = os.ReadFile(deadLetterPath)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, strings.TrimSuffix(files[deadLetterPath], "\n"), lines[0], "the dead letters before the checkpoint are kept")
+		assert.Contains(t, lines[1], "\"record\":{\"id\":2}")
+	}
+
+	_, err = os.Stat(checkpointPath)
+	assert.ErrorIs(t, err, os.ErrNotExist, "checkpoint should be cleared after the migration")
+}
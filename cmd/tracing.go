@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+)
+
+// setupTracing sets the global TracerProvider with the exporter, and returns a function which flushes the spans.
+//
+// The exporter is one of:
+//   - otlp: OTLP over HTTP. The endpoint and headers are configured by OTEL_EXPORTER_OTLP_* environment variables
+//   - stdout: JSON lines to stdout
+//   - file: JSON lines to the file at path
+func setupTracing(ctx context.Context, exporter string, path string) (func(), error) {
+	var spanExporter sdktrace.SpanExporter
+	var file io.Closer
+
+	switch exporter {
+	case "otlp":
+		e, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		spanExporter = e
+	case "stdout":
+		e, err := stdouttrace.New()
+		if err != nil {
+			return nil, err
+		}
+
+		spanExporter = e
+	case "file":
+		if path == "" {
+			return nil, fmt.Errorf("--trace-file is required for the file exporter")
+		}
+
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		spanExporter = e
+		file = f
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %v", exporter)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(attribute.String("service.name", "gallon")),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := provider.Shutdown(ctx); err != nil {
+			zap.S().Errorw("Failed to flush traces", "error", err)
+		}
+
+		if file != nil {
+			if err := file.Close(); err != nil {
+				zap.S().Errorw("Failed to close trace file", "error", err)
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/myuon/gallon/gallon"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	ValidateCmd.Flags().BoolVar(&withTemplate, "template", false, "parse the config file as a Go's text/template")
+	ValidateCmd.Flags().BoolVar(&withTemplateWithEnv, "template-with-env", false, "parse the config file as a Go's text/template with environment variables injected")
+}
+
+// ValidateCmd defines `gallon validate` command.
+var ValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate config files without running the migrations",
+	Args:  cobra.ExactArgs(1),
+	// The problems are printed for each file, so the usage is not printed for invalid files.
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ValidateGallonWithPath(cmd.OutOrStdout(), args[0], RunGallonOptions{
+			AsTemplate: withTemplate || withTemplateWithEnv,
+			WithEnv:    withTemplateWithEnv,
+		})
+	},
+}
+
+// ValidateGallonWithPath validates the config files matched by the glob pattern (See gallon.ValidateConfig),
+// and prints the problems as `<file>:<line>:<column>: <message>` to w.
+// The templates are rendered with opts, and no connection to the source or the destination is made.
+//
+// It returns an error if any file is invalid.
+func ValidateGallonWithPath(w io.Writer, configPath string, opts RunGallonOptions) error {
+	files, err := filepath.Glob(configPath)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no config files matched: %v", configPath)
+	}
+
+	invalid := 0
+	for _, file := range files {
+		errs, issues := validateGallonFile(file, opts)
+		for _, issue := range issues {
+			fmt.Fprintf(w, "%v: warning: %v\n", file, issue)
+		}
+		if len(errs) == 0 {
+			fmt.Fprintf(w, "%v: ok\n", file)
+			continue
+		}
+
+		invalid++
+		for _, err := range errs {
+			switch {
+			case err.Line == 0:
+				fmt.Fprintf(w, "%v: %v\n", file, err.Message)
+			case err.Column == 0:
+				fmt.Fprintf(w, "%v:%v: %v\n", file, err.Line, err.Message)
+			default:
+				fmt.Fprintf(w, "%v:%v:%v: %v\n", file, err.Line, err.Column, err.Message)
+			}
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%v of %v config files are invalid", invalid, len(files))
+	}
+
+	return nil
+}
+
+// validateGallonFile returns the problems of the config file, and the schema issues which are warned by `gallon run`.
+func validateGallonFile(file string, opts RunGallonOptions) ([]gallon.ConfigError, []gallon.SchemaIssue) {
+	configFileBody, err := os.ReadFile(file)
+	if err != nil {
+		return []gallon.ConfigError{{Message: err.Error()}}, nil
+	}
+
+	// the line numbers are of the rendered config
+	configBytes, err := renderConfig(configFileBody, opts)
+	if err != nil {
+		return []gallon.ConfigError{{Message: err.Error()}}, nil
+	}
+
+	errs := gallon.ValidateConfig(configBytes)
+	if len(errs) > 0 {
+		return errs, nil
+	}
+
+	issues, err := gallon.CheckSchemaCompatibility(configBytes)
+	if err != nil {
+		return []gallon.ConfigError{{Message: err.Error()}}, nil
+	}
+
+	return nil, issues
+}
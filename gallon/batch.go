@@ -0,0 +1,174 @@
+package gallon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// BatchPolicy re-chunks the batches sent to each output, so that the batch size of the outputs does not depend on the page size of the input.
+// Zero (or unset) values keep the batches of the input as they are.
+type BatchPolicy struct {
+	// Size is the maximum number of records in a batch. The records are buffered until the batch is full.
+	Size int `yaml:"size"`
+	// FlushInterval is the maximum time to buffer the records. A partial batch is sent after the interval.
+	// If Size is not set, the records received within the interval are sent as a batch.
+	FlushInterval time.Duration `yaml:"flushInterval"`
+}
+
+// Validate returns an error if the size or the interval is negative.
+func (p BatchPolicy) Validate() error {
+	if p.Size < 0 || p.FlushInterval < 0 {
+		return errors.New("batch must not be negative")
+	}
+
+	return nil
+}
+
+func (p BatchPolicy) enabled() bool {
+	return p.Size > 0 || p.FlushInterval > 0
+}
+
+// outputBatcher re-chunks the batches for an output by BatchPolicy.
+// A nil outputBatcher passes the batches through.
+type outputBatcher struct {
+	policy BatchPolicy
+
+	records []GallonRecord
+	// ends are the end offsets in records of the batches received but not yet completely sent
+	ends []int
+
+	mu sync.Mutex
+	// completed is the number of received batches completed by each chunk sent to the output, which is consumed by acked
+	completed []int
+}
+
+func newOutputBatcher(policy BatchPolicy) *outputBatcher {
+	if !policy.enabled() {
+		return nil
+	}
+
+	return &outputBatcher{policy: policy}
+}
+
+// acked returns the number of received batches completed by the oldest chunk not yet acknowledged by the output.
+func (b *outputBatcher) acked() int {
+	if b == nil {
+		return 1
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.completed) == 0 {
+		return 0
+	}
+
+	n := b.completed[0]
+	b.completed = b.completed[1:]
+
+	return n
+}
+
+func (b *outputBatcher) add(records []GallonRecord) {
+	b.records = append(b.records, records...)
+	b.ends = append(b.ends, len(b.records))
+}
+
+// next takes a full chunk from the buffer, or the remaining records if flush is true.
+func (b *outputBatcher) next(flush bool) ([]GallonRecord, bool) {
+	n := b.policy.Size
+	if n <= 0 || len(b.records) < n {
+		if !flush || len(b.records) == 0 {
+			return nil, false
+		}
+
+		n = len(b.records)
+	}
+
+	chunk := b.records[:n:n]
+	b.records = b.records[n:]
+
+	completed := 0
+	for len(b.ends) > 0 && b.ends[0] <= n {
+		completed++
+		b.ends = b.ends[1:]
+	}
+	for i := range b.ends {
+		b.ends[i] -= n
+	}
+
+	b.mu.Lock()
+	b.completed = append(b.completed, completed)
+	b.mu.Unlock()
+
+	return chunk, true
+}
+
+// run receives the batches from source and sends the chunks to sink until source is closed.
+// It stops when ctx is cancelled or failed is closed (i.e. the output has returned).
+func (b *outputBatcher) run(ctx context.Context, source <-chan []GallonRecord, sink chan<- []GallonRecord, failed <-chan struct{}) {
+	defer close(sink)
+
+	send := func(flush bool) bool {
+		for {
+			chunk, ok := b.next(flush)
+			if !ok {
+				return true
+			}
+
+			select {
+			case <-ctx.Done():
+				return false
+			case <-failed:
+				return false
+			case sink <- chunk:
+			}
+		}
+	}
+
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	if b.policy.FlushInterval > 0 {
+		timer = time.NewTimer(b.policy.FlushInterval)
+		timer.Stop()
+		defer timer.Stop()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeout:
+			timeout = nil
+			if !send(true) {
+				return
+			}
+		case records, ok := <-source:
+			if !ok {
+				send(true)
+				return
+			}
+
+			b.add(records)
+			buffered := len(b.records)
+			if !send(false) {
+				return
+			}
+
+			if timer == nil {
+				continue
+			}
+
+			if len(b.records) == 0 {
+				timer.Stop()
+				timeout = nil
+			} else if timeout == nil || len(b.records) < buffered {
+				// the interval starts from the oldest buffered record
+				timer.Reset(b.policy.FlushInterval)
+				timeout = timer.C
+			}
+		}
+	}
+}
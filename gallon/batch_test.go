@@ -0,0 +1,217 @@
+package gallon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+)
+
+// outputPluginBatchSizes records the size of each batch
+type outputPluginBatchSizes struct {
+	mu    sync.Mutex
+	sizes []int
+}
+
+var _ OutputPlugin = &outputPluginBatchSizes{}
+
+func (p *outputPluginBatchSizes) ReplaceLogger(logger logr.Logger) {
+}
+
+func (p *outputPluginBatchSizes) Cleanup() error {
+	return nil
+}
+
+func (p *outputPluginBatchSizes) Load(ctx context.Context, messages chan []GallonRecord, errs chan error) error {
+	for msgs := range messages {
+		p.mu.Lock()
+		p.sizes = append(p.sizes, len(msgs))
+		p.mu.Unlock()
+	}
+
+	return nil
+}
+
+// inputPluginSlow sends the batches at the interval
+type inputPluginSlow struct {
+	data     [][]GallonRecord
+	interval time.Duration
+}
+
+var _ InputPlugin = &inputPluginSlow{}
+
+func (p *inputPluginSlow) ReplaceLogger(logger logr.Logger) {
+}
+
+func (p *inputPluginSlow) Cleanup() error {
+	return nil
+}
+
+func (p *inputPluginSlow) Extract(ctx context.Context, messages chan []GallonRecord, errs chan error) error {
+	for i, msgs := range p.data {
+		if i > 0 {
+			time.Sleep(p.interval)
+		}
+
+		messages <- msgs
+	}
+
+	return nil
+}
+
+func newBatchTestData(sizes ...int) [][]GallonRecord {
+	data := [][]GallonRecord{}
+	id := 0
+	for _, size := range sizes {
+		page := []GallonRecord{}
+		for i := 0; i < size; i++ {
+			r := NewGallonRecord()
+			r.Set("id", fmt.Sprintf("%v", id))
+			id++
+
+			page = append(page, r)
+		}
+
+		data = append(data, page)
+	}
+
+	return data
+}
+
+func Test_batch(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    InputPlugin
+		policy   BatchPolicy
+		expected []int
+	}{
+		{
+			name:     "no policy",
+			input:    NewInputPluginStub(newBatchTestData(3, 3, 3, 1)),
+			expected: []int{3, 3, 3, 1},
+		},
+		{
+			name:     "size",
+			input:    NewInputPluginStub(newBatchTestData(3, 3, 3, 1)),
+			policy:   BatchPolicy{Size: 4},
+			expected: []int{4, 4, 2},
+		},
+		{
+			name:     "size larger than pages",
+			input:    NewInputPluginStub(newBatchTestData(3, 3, 3, 1)),
+			policy:   BatchPolicy{Size: 100},
+			expected: []int{10},
+		},
+		{
+			name:     "flush interval",
+			input:    &inputPluginSlow{data: newBatchTestData(1, 1, 1), interval: 500 * time.Millisecond},
+			policy:   BatchPolicy{Size: 100, FlushInterval: 50 * time.Millisecond},
+			expected: []int{1, 1, 1},
+		},
+		{
+			name:     "flush interval without size",
+			input:    NewInputPluginStub(newBatchTestData(3, 3, 3, 1)),
+			policy:   BatchPolicy{FlushInterval: 10 * time.Second},
+			expected: []int{10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := &outputPluginBatchSizes{}
+
+			g := Gallon{
+				Logger: logger,
+				Input:  tt.input,
+				Output: output,
+				Batch:  tt.policy,
+			}
+
+			result, err := g.RunWithResult(context.Background())
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expected, output.sizes)
+
+			total := 0
+			for _, size := range tt.expected {
+				total += size
+			}
+			assert.Equal(t, total, result.LoadedRecords)
+		})
+	}
+}
+
+func Test_batch_checkpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    [][]GallonRecord
+		policy  BatchPolicy
+		limit   int
+		cursor  string
+		batches int
+	}{
+		{
+			name:    "a batch completes multiple pages",
+			data:    newBatchTestData(1, 1, 1, 1),
+			policy:  BatchPolicy{Size: 2},
+			limit:   3,
+			cursor:  "2",
+			batches: 2,
+		},
+		{
+			name:    "a batch completes a page partially",
+			data:    newBatchTestData(2, 2),
+			policy:  BatchPolicy{Size: 3},
+			limit:   3,
+			cursor:  "1",
+			batches: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewCheckpointStoreFile(filepath.Join(t.TempDir(), "state.json"))
+
+			output, err := NewOutputPluginFileFromConfig([]byte(`
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual
+`))
+			if err != nil {
+				t.Errorf("Could not create plugin: %s", err)
+			}
+			output.newWriter = func() (io.WriteCloser, error) {
+				return &failingWriter{buf: new(bytes.Buffer), limit: tt.limit}, nil
+			}
+
+			g := Gallon{
+				Logger:     logger,
+				Input:      NewInputPluginStub(tt.data),
+				Output:     output,
+				Checkpoint: store,
+				Batch:      tt.policy,
+			}
+
+			err = g.Run(context.Background())
+			assert.Error(t, err)
+
+			checkpoint, err := store.Load(context.Background())
+			if err != nil {
+				t.Fatalf("Could not load checkpoint: %s", err)
+			}
+			if checkpoint == nil {
+				t.Fatalf("Expected checkpoint to be saved")
+			}
+			assert.Equal(t, tt.cursor, string(checkpoint.Cursor))
+			assert.Equal(t, tt.batches, checkpoint.Batches)
+		})
+	}
+}
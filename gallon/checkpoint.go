@@ -0,0 +1,282 @@
+package gallon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Checkpoint is the position of the input plugin up to which the records have been durably loaded.
+type Checkpoint struct {
+	// Cursor is the position given by the input plugin (See ResumableInputPlugin).
+	Cursor json.RawMessage `json:"cursor"`
+	// Batches is the number of batches loaded since the migration started (or resumed).
+	Batches   int       `json:"batches"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CheckpointStore persists the checkpoint of a migration.
+type CheckpointStore interface {
+	// Load returns the saved checkpoint, or nil if there is none.
+	Load(ctx context.Context) (*Checkpoint, error)
+	Save(ctx context.Context, checkpoint Checkpoint) error
+	// Clear removes the saved checkpoint. It is called when the migration has finished successfully.
+	Clear(ctx context.Context) error
+}
+
+// ResumableInputPlugin is an InputPlugin which can restart the extraction from a cursor.
+type ResumableInputPlugin interface {
+	InputPlugin
+
+	// Resume makes Extract start from the cursor of a checkpoint.
+	Resume(cursor json.RawMessage) error
+	// SetCursorHandler sets the function which must be called with the cursor of each batch, right before the batch is sent to the messages channel.
+	// The cursor is the position right after the batch, from which Extract can restart.
+	SetCursorHandler(handler func(cursor json.RawMessage))
+}
+
+// AckOutputPlugin is an OutputPlugin which acknowledges each batch as soon as it is durably loaded.
+// Batches sent to other output plugins are never acknowledged, since Load may return without an error when it is cancelled,
+// so the checkpoint is not saved for them.
+type AckOutputPlugin interface {
+	OutputPlugin
+
+	// SetAckHandler sets the function which must be called once for each batch received from the messages channel,
+	// in the order of receiving, when the batch is durably loaded.
+	SetAckHandler(ack func())
+}
+
+// CheckpointStoreFile saves the checkpoint as a JSON file in the local file system.
+type CheckpointStoreFile struct {
+	path string
+}
+
+func NewCheckpointStoreFile(path string) *CheckpointStoreFile {
+	return &CheckpointStoreFile{
+		path: path,
+	}
+}
+
+var _ CheckpointStore = &CheckpointStoreFile{}
+
+func (s *CheckpointStoreFile) Load(ctx context.Context) (*Checkpoint, error) {
+	bs, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(bs, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %v (error: %v)", s.path, err)
+	}
+
+	return &checkpoint, nil
+}
+
+func (s *CheckpointStoreFile) Save(ctx context.Context, checkpoint Checkpoint) error {
+	bs, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file and rename it, so that the checkpoint is not corrupted by a crash
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s *CheckpointStoreFile) Clear(ctx context.Context) error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// CheckpointConfig is the schema of the `checkpoint` section.
+type CheckpointConfig struct {
+	Type string `yaml:"type"`
+	// Path is the path of the state file for `type: file`
+	Path string `yaml:"path"`
+}
+
+func NewCheckpointStoreFromConfig(config CheckpointConfig) (CheckpointStore, error) {
+	switch config.Type {
+	case "file":
+		if config.Path == "" {
+			return nil, errors.New("path is required for file checkpoint")
+		}
+
+		return NewCheckpointStoreFile(config.Path), nil
+	default:
+		return nil, fmt.Errorf("checkpoint store not found: %v", config.Type)
+	}
+}
+
+// checkpointer tracks the batches from the input to the outputs, and saves the cursor of the last batch
+// such that it and all the batches before it have been acknowledged by every output.
+// All the methods are no-op for a nil checkpointer.
+type checkpointer struct {
+	logger logr.Logger
+	store  CheckpointStore
+
+	mu sync.Mutex
+	// cursors are the cursors reported by the input plugin which are not yet assigned to a batch
+	cursors []json.RawMessage
+	// batchCursors are the cursors of the batches by sequence number
+	batchCursors map[int]json.RawMessage
+	// pending is the number of outputs which have not acknowledged the batch yet
+	pending map[int]int
+	// sent is the sequence numbers of the batches sent to each output, which are not acknowledged yet
+	sent [][]int
+	// next is the sequence number of the oldest batch which is not acknowledged yet
+	next int
+}
+
+func newCheckpointer(logger logr.Logger, store CheckpointStore, outputs int) *checkpointer {
+	return &checkpointer{
+		logger:       logger,
+		store:        store,
+		batchCursors: map[int]json.RawMessage{},
+		pending:      map[int]int{},
+		sent:         make([][]int, outputs),
+	}
+}
+
+// reportCursor is the cursor handler for ResumableInputPlugin.
+func (c *checkpointer) reportCursor(cursor json.RawMessage) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cursors = append(c.cursors, cursor)
+}
+
+// extracted assigns the oldest reported cursor to the batch.
+func (c *checkpointer) extracted(seq int) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.cursors) == 0 {
+		return
+	}
+
+	c.batchCursors[seq] = c.cursors[0]
+	c.cursors = c.cursors[1:]
+}
+
+// broadcasting must be called before the batch is sent to the outputs.
+func (c *checkpointer) broadcasting(seq int, outputs int) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending[seq] = outputs
+}
+
+// sending must be called before the batch is sent to the output.
+func (c *checkpointer) sending(output int, seq int) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sent[output] = append(c.sent[output], seq)
+}
+
+// ack acknowledges the oldest batch sent to the output.
+func (c *checkpointer) ack(ctx context.Context, output int) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.sent[output]) == 0 {
+		return
+	}
+
+	c.pending[c.sent[output][0]]--
+	c.sent[output] = c.sent[output][1:]
+
+	c.advance(ctx)
+}
+
+// skip marks the batch as done without sending it to the outputs, e.g. when all the records are dropped by a transform.
+func (c *checkpointer) skip(ctx context.Context, seq int) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending[seq] = 0
+
+	c.advance(ctx)
+}
+
+func (c *checkpointer) advance(ctx context.Context) {
+	var cursor json.RawMessage
+	for {
+		remaining, ok := c.pending[c.next]
+		if !ok || remaining > 0 {
+			break
+		}
+
+		if batchCursor, ok := c.batchCursors[c.next]; ok {
+			cursor = batchCursor
+		}
+
+		delete(c.pending, c.next)
+		delete(c.batchCursors, c.next)
+		c.next++
+	}
+
+	if cursor == nil {
+		return
+	}
+
+	if err := c.store.Save(ctx, Checkpoint{Cursor: cursor, Batches: c.next, UpdatedAt: time.Now()}); err != nil {
+		c.logger.Error(err, "failed to save checkpoint")
+	}
+}
@@ -0,0 +1,174 @@
+package gallon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingWriter fails after writing `limit` records
+type failingWriter struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.limit == 0 {
+		return 0, errors.New("disk full")
+	}
+	w.limit--
+
+	return w.buf.Write(p)
+}
+
+func (w *failingWriter) Close() error {
+	return nil
+}
+
+func Test_checkpoint_resume(t *testing.T) {
+	data := [][]GallonRecord{}
+	for i := 0; i < 4; i++ {
+		r := NewGallonRecord()
+		r.Set("id", fmt.Sprintf("%v", i))
+
+		data = append(data, []GallonRecord{r})
+	}
+
+	store := NewCheckpointStoreFile(filepath.Join(t.TempDir(), "state.json"))
+	buf := new(bytes.Buffer)
+
+	output, err := NewOutputPluginFileFromConfig([]byte(`
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+	output.newWriter = func() (io.WriteCloser, error) {
+		return &failingWriter{buf: buf, limit: 2}, nil
+	}
+
+	g := Gallon{
+		Logger:     logger,
+		Input:      NewInputPluginStub(data),
+		Output:     output,
+		Checkpoint: store,
+	}
+
+	err = g.Run(context.Background())
+	assert.Error(t, err)
+
+	checkpoint, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Could not load checkpoint: %s", err)
+	}
+	if checkpoint == nil {
+		t.Fatalf("Expected checkpoint to be saved")
+	}
+	assert.Equal(t, "2", string(checkpoint.Cursor))
+	assert.Equal(t, 2, checkpoint.Batches)
+
+	input := NewInputPluginStub(data)
+	if err := input.Resume(checkpoint.Cursor); err != nil {
+		t.Fatalf("Could not resume: %s", err)
+	}
+
+	writer := bufio.NewWriter(buf)
+	output.newWriter = func() (io.WriteCloser, error) {
+		return NewNopWriteCloser(writer), nil
+	}
+
+	g.Input = input
+	if err := g.Run(context.Background()); err != nil {
+		t.Errorf("Could not run command: %s", err)
+	}
+
+	assert.Equal(t, "{\"id\":\"0\"}\n{\"id\":\"1\"}\n{\"id\":\"2\"}\n{\"id\":\"3\"}\n", buf.String())
+
+	checkpoint, err = store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, checkpoint, "checkpoint should be cleared after the migration")
+}
+
+func Test_checkpoint_not_acknowledged_on_cancel(t *testing.T) {
+	data := [][]GallonRecord{}
+	for i := 0; i < 100; i++ {
+		r := NewGallonRecord()
+		r.Set("id", fmt.Sprintf("%v", i))
+
+		data = append(data, []GallonRecord{r})
+	}
+
+	store := NewCheckpointStoreFile(filepath.Join(t.TempDir(), "state.json"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the output returns without an error when it is cancelled, which does not mean the records are loaded
+	g := Gallon{
+		Logger:     logger,
+		Input:      NewInputPluginStub(data),
+		Output:     &outputPluginFlushOnCancel{onLoad: cancel},
+		Checkpoint: store,
+	}
+
+	err := g.Run(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	checkpoint, err := store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, checkpoint, "checkpoint should not be saved for the output without acknowledgements")
+}
+
+func Test_checkpointer(t *testing.T) {
+	store := NewCheckpointStoreFile(filepath.Join(t.TempDir(), "state.json"))
+	c := newCheckpointer(logger, store, 2)
+	ctx := context.Background()
+
+	latest := func() string {
+		checkpoint, err := store.Load(ctx)
+		if err != nil {
+			t.Fatalf("Could not load checkpoint: %s", err)
+		}
+		if checkpoint == nil {
+			return ""
+		}
+
+		return string(checkpoint.Cursor)
+	}
+
+	for seq := 0; seq < 3; seq++ {
+		c.reportCursor([]byte(fmt.Sprintf("%v", seq+1)))
+		c.extracted(seq)
+	}
+
+	// batch 1 is dropped by a transform
+	c.broadcasting(0, 2)
+	c.sending(0, 0)
+	c.sending(1, 0)
+	c.skip(ctx, 1)
+	c.broadcasting(2, 2)
+	c.sending(0, 2)
+	c.sending(1, 2)
+
+	c.ack(ctx, 0)
+	assert.Equal(t, "", latest(), "batch 0 is not acknowledged by output 1")
+
+	c.ack(ctx, 0)
+	assert.Equal(t, "", latest(), "batch 0 is not acknowledged by output 1")
+
+	c.ack(ctx, 1)
+	assert.Equal(t, "2", latest(), "batch 0 and 1 are done")
+
+	c.ack(ctx, 1)
+	assert.Equal(t, "3", latest())
+}
@@ -0,0 +1,366 @@
+package gallon
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configSchemaDescriptions are the descriptions of the fields in the JSON Schema of the config, by the yaml keys.
+var configSchemaDescriptions = map[reflect.Type]map[string]string{
+	reflect.TypeFor[GallonConfig[yaml.Node, yaml.Node]](): {
+		"in":              "Input plugin",
+		"out":             "Output plugin. It can be omitted if outs is given",
+		"outs":            "Additional output plugins loading the same records",
+		"onOutputFailure": "What happens to the other outputs when one of them fails (default: abort)",
+		"errors":          "How many non-fatal errors are tolerated",
+		"deadLetter":      "Output plugin for the rejected records",
+		"transforms":      "Transforms applied to the records in order",
+		"checkpoint":      "Where the position of the input is saved to resume the migration",
+		"rateLimit":       "Maximum throughput of the migration",
+		"batch":           "Re-chunks the batches sent to each output",
+		"verify":          "Compares the number of records in the source with the outputs after the migration",
+	},
+	reflect.TypeFor[ErrorPolicy](): {
+		"max":           "Maximum number of errors (default: 50 unless maxPercentage is set)",
+		"maxPercentage": "Maximum percentage (0-100) of rejected records, checked after the migration",
+		"failFast":      "Cancel the migration on the first error",
+	},
+	reflect.TypeFor[RateLimit](): {
+		"recordsPerSecond": "Maximum number of records per second",
+		"batchesPerSecond": "Maximum number of batches (pages of the input) per second",
+	},
+	reflect.TypeFor[BatchPolicy](): {
+		"size":          "Maximum number of records in a batch",
+		"flushInterval": "Maximum time to buffer the records, e.g. 10s",
+	},
+	reflect.TypeFor[VerifyPolicy](): {
+		"tolerance":           "Maximum difference of the numbers (default: 0)",
+		"tolerancePercentage": "Maximum difference in percentage (0-100) of the number of records in the source",
+	},
+	reflect.TypeFor[CheckpointConfig](): {
+		"type": "Type of the checkpoint store",
+		"path": "Path of the state file for type: file",
+	},
+	reflect.TypeFor[InputPluginSqlConfig](): {
+		"table":        "Table to extract",
+		"query":        "SQL query to extract instead of table (the raw query mode, schema is ignored)",
+		"database_url": "Database url (DSN) of the driver",
+		"driver":       "Database driver",
+		"pageSize":     "Number of rows in a page (default: 1000)",
+		"schema":       "Columns to extract",
+	},
+	reflect.TypeFor[InputPluginSqlConfigSchemaColumn](): {
+		"type":             "Type of the column",
+		"default_timezone": "Timezone of the time without timezone, e.g. Asia/Tokyo",
+		"transforms":       "Conversions of the value",
+		"rename":           "Name of the column in the records",
+	},
+	reflect.TypeFor[InputPluginSqlConfigSchemaColumnTransform](): {
+		"type":   "Type after the conversion",
+		"format": "Go time format for time to string",
+		"as":     "Unit of int to time (unix)",
+		"tz":     "Timezone to convert the time into",
+	},
+	reflect.TypeFor[InputPluginDynamoDbConfig](): {
+		"table":    "Table to scan",
+		"schema":   "Attributes to extract",
+		"region":   "AWS region",
+		"endpoint": "Endpoint, e.g. for DynamoDB Local",
+		"pageSize": "Number of items in a page (default: 1000)",
+	},
+	reflect.TypeFor[InputPluginDynamoDbConfigSchemaColumn](): {
+		"type":       "Type of the attribute",
+		"properties": "Attributes of an object",
+		"items":      "Type of the items of an array",
+		"rename":     "Name of the column in the records",
+	},
+	reflect.TypeFor[InputPluginRandomConfig](): {
+		"pageSize":  "Number of records in a page (default: 10)",
+		"pageLimit": "Number of pages (default: 10)",
+		"schema":    "Columns to generate",
+	},
+	reflect.TypeFor[InputPluginRandomConfigSchemaColumn](): {
+		"type":   "Kind of the generated values",
+		"min":    "Minimum value of int",
+		"max":    "Maximum value of int",
+		"format": "Format of time (rfc3339)",
+		"fields": "Fields of a record",
+	},
+	reflect.TypeFor[ExecConfig](): {
+		"command": "Command to run",
+		"args":    "Arguments of the command",
+		"env":     "Environment variables added to the command",
+		"config":  "Config sent to the command in the init message",
+	},
+	reflect.TypeFor[OutputPluginBigQueryConfig](): {
+		"projectId":            "GCP project ID",
+		"datasetId":            "BigQuery dataset ID",
+		"tableId":              "BigQuery table ID",
+		"location":             "BigQuery location",
+		"endpoint":             "Endpoint, e.g. for bigquery-emulator",
+		"schema":               "Columns of the table. Inferred from the input if omitted",
+		"deleteTemporaryTable": "Delete the temporary table after copying (default: true)",
+		"append":               "Append the records to the table instead of replacing it",
+	},
+	reflect.TypeFor[OutputPluginBigQueryConfigSchemaColumn](): {
+		"type":     "Type of the column",
+		"fields":   "Fields of a record",
+		"repeated": "The column is an array of the type",
+	},
+	reflect.TypeFor[OutputPluginFileConfig](): {
+		"filepath": "Path of the file",
+		"format":   "Format of the file",
+		"header":   "Write the names of the columns at the first line (csv only)",
+		"append":   "Append the records to the file instead of overwriting it",
+	},
+	reflect.TypeFor[OutputPluginStdoutConfig](): {
+		"format": "json, or Go's default format of the records otherwise",
+	},
+	reflect.TypeFor[TransformPluginRenameConfig](): {
+		"columns": "Map from the current column name to the new name",
+	},
+	reflect.TypeFor[TransformPluginDropConfig](): {
+		"columns": "Columns to remove",
+	},
+	reflect.TypeFor[TransformPluginCastConfig](): {
+		"columns": "Map from the column name to the type",
+		"format":  "Go time format for time <-> string (default: RFC3339)",
+	},
+	reflect.TypeFor[TransformPluginComputeConfig](): {
+		"column":   "Column to set",
+		"template": "Go's text/template rendered with the record, e.g. {{.first_name}}",
+	},
+}
+
+// configSchemaEnums are the allowed values of the string fields (or the values of the map fields) in the JSON Schema of the config.
+var configSchemaEnums = map[reflect.Type]map[string][]string{
+	reflect.TypeFor[GallonConfig[yaml.Node, yaml.Node]](): {
+		"onOutputFailure": {string(OutputFailureAbort), string(OutputFailureContinue)},
+	},
+	reflect.TypeFor[CheckpointConfig](): {
+		"type": {"file"},
+	},
+	reflect.TypeFor[InputPluginSqlConfig](): {
+		"driver": {"mysql", "postgres"},
+	},
+	reflect.TypeFor[InputPluginSqlConfigSchemaColumn](): {
+		"type": sqlColumnRule.types,
+	},
+	reflect.TypeFor[InputPluginDynamoDbConfigSchemaColumn](): {
+		"type": dynamoDbColumnRule.types,
+	},
+	reflect.TypeFor[InputPluginRandomConfigSchemaColumn](): {
+		"type": randomColumnRule.types,
+	},
+	reflect.TypeFor[OutputPluginFileConfig](): {
+		"format": {"jsonl", "csv"},
+	},
+	reflect.TypeFor[TransformPluginCastConfig](): {
+		"columns": {"string", "int", "float", "bool", "time"},
+	},
+}
+
+// configSchemaFoldedEnums are the allowed values of the string fields which are case-insensitive (See columnRule.fold).
+// They are completed in lower case, and any case is accepted by a pattern.
+var configSchemaFoldedEnums = map[reflect.Type]map[string][]string{
+	reflect.TypeFor[OutputPluginBigQueryConfigSchemaColumn](): {
+		"type": bigQueryColumnRule.types,
+	},
+}
+
+// configSchemaRequired are the required fields in the JSON Schema of the config. `type` of the plugins is required in any case.
+var configSchemaRequired = map[reflect.Type][]string{
+	reflect.TypeFor[CheckpointConfig]():                       {"type"},
+	reflect.TypeFor[ExecConfig]():                             {"command"},
+	reflect.TypeFor[InputPluginSqlConfigSchemaColumn]():       {"type"},
+	reflect.TypeFor[InputPluginDynamoDbConfigSchemaColumn]():  {"type"},
+	reflect.TypeFor[InputPluginRandomConfigSchemaColumn]():    {"type"},
+	reflect.TypeFor[OutputPluginBigQueryConfigSchemaColumn](): {"type"},
+	reflect.TypeFor[TransformPluginComputeConfig]():           {"column", "template"},
+}
+
+// foldedEnumSchema returns the schema of a string which is one of the values in any case, e.g. `[iI][nN][tT]` for `int`.
+func foldedEnumSchema(values []string) map[string]any {
+	lower := []any{}
+	patterns := []string{}
+	for _, value := range values {
+		lower = append(lower, strings.ToLower(value))
+
+		pattern := ""
+		for _, r := range value {
+			if l, u := unicode.ToLower(r), unicode.ToUpper(r); l != u {
+				pattern += "[" + string(l) + string(u) + "]"
+			} else {
+				pattern += regexp.QuoteMeta(string(r))
+			}
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	return map[string]any{
+		"type":  "string",
+		"anyOf": []any{map[string]any{"enum": lower}, map[string]any{"pattern": "^(" + strings.Join(patterns, "|") + ")$"}},
+	}
+}
+
+// ConfigJSONSchema returns the JSON Schema (draft-07) of the config file, for editors to validate and complete it.
+// The `in`, `out`, `outs`, `deadLetter` and `transforms` sections are unions discriminated by `type`.
+// The plugins registered by other packages are accepted as a `type`, but their fields are not described.
+func ConfigJSONSchema() map[string]any {
+	b := &configSchemaBuilder{definitions: map[string]any{}}
+
+	b.definitions["input"] = b.pluginSchema("Input plugin", InputPlugins(), inputConfigTypes)
+	b.definitions["output"] = b.pluginSchema("Output plugin", OutputPlugins(), outputConfigTypes)
+	b.definitions["transform"] = b.pluginSchema("Transform plugin", TransformPlugins(), transformConfigTypes)
+
+	schema := b.objectSchema(reflect.TypeFor[GallonConfig[yaml.Node, yaml.Node]]())
+	properties := schema["properties"].(map[string]any)
+	for key, ref := range map[string]map[string]any{
+		"in":         {"$ref": "#/definitions/input"},
+		"out":        {"$ref": "#/definitions/output"},
+		"outs":       {"type": "array", "items": map[string]any{"$ref": "#/definitions/output"}},
+		"deadLetter": {"$ref": "#/definitions/output"},
+		"transforms": {"type": "array", "items": map[string]any{"$ref": "#/definitions/transform"}},
+	} {
+		ref["description"] = properties[key].(map[string]any)["description"]
+		properties[key] = ref
+	}
+
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "gallon config"
+	schema["required"] = []string{"in"}
+	schema["definitions"] = b.definitions
+
+	return schema
+}
+
+type configSchemaBuilder struct {
+	// definitions are the schemas of the struct types by the names, which may be recursive
+	definitions map[string]any
+}
+
+// pluginSchema returns the union of the plugins discriminated by `type`.
+func (b *configSchemaBuilder) pluginSchema(description string, types []string, configTypes map[string]reflect.Type) map[string]any {
+	conditions := []any{}
+	for _, t := range types {
+		configType, ok := configTypes[t]
+		if !ok {
+			continue
+		}
+
+		then := b.objectSchema(configType)
+		then["properties"].(map[string]any)["type"] = map[string]any{"const": t}
+
+		conditions = append(conditions, map[string]any{
+			"if": map[string]any{
+				"properties": map[string]any{"type": map[string]any{"const": t}},
+				"required":   []string{"type"},
+			},
+			"then": then,
+		})
+	}
+
+	return map[string]any{
+		"description": description,
+		"type":        "object",
+		"properties": map[string]any{
+			"type": map[string]any{"description": "Type of the plugin", "enum": types},
+		},
+		"required": []string{"type"},
+		"allOf":    conditions,
+	}
+}
+
+// objectSchema returns the schema of the struct, whose unknown fields are not allowed.
+func (b *configSchemaBuilder) objectSchema(t reflect.Type) map[string]any {
+	fields := yamlFields(t)
+	properties := map[string]any{}
+	for key, ft := range fields {
+		field := b.typeSchema(ft)
+
+		if enum, ok := configSchemaFoldedEnums[t][key]; ok {
+			field = foldedEnumSchema(enum)
+		}
+
+		if enum, ok := configSchemaEnums[t][key]; ok {
+			if additional, ok := field["additionalProperties"].(map[string]any); ok {
+				additional["enum"] = enum
+			} else {
+				field["enum"] = enum
+			}
+		}
+
+		if description, ok := configSchemaDescriptions[t][key]; ok {
+			if _, ok := field["$ref"]; ok {
+				// the siblings of $ref are ignored in draft-07
+				field = map[string]any{"allOf": []any{field}}
+			}
+			field["description"] = description
+		}
+
+		properties[key] = field
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if required, ok := configSchemaRequired[t]; ok {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// typeSchema returns the schema of the values of the type decoded by yaml.v3.
+func (b *configSchemaBuilder) typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case reflect.TypeFor[yaml.Node]():
+		return map[string]any{}
+	case reflect.TypeFor[time.Duration]():
+		// e.g. `10s`, or nanoseconds
+		return map[string]any{"type": []string{"string", "integer"}}
+	}
+
+	// orderedmap.OrderedMap decodes itself as a mapping, whose value type is the result of Get
+	if get, ok := reflect.PointerTo(t).MethodByName("Get"); ok && strings.HasPrefix(t.Name(), "OrderedMap[") {
+		t = reflect.MapOf(get.Type.In(1), get.Type.Out(0))
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := b.definitions[name]; !ok {
+			// the placeholder stops the recursion of the nested columns
+			b.definitions[name] = nil
+			b.definitions[name] = b.objectSchema(t)
+		}
+
+		return map[string]any{"$ref": "#/definitions/" + name}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.typeSchema(t.Elem())}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": b.typeSchema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+}
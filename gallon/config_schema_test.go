@@ -0,0 +1,94 @@
+package gallon
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_config_json_schema(t *testing.T) {
+	j, err := json.Marshal(ConfigJSONSchema())
+	if err != nil {
+		t.Fatalf("Could not marshal schema: %s", err)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(j, &schema); err != nil {
+		t.Fatalf("Could not unmarshal schema: %s", err)
+	}
+
+	definitions := schema["definitions"].(map[string]any)
+
+	// every $ref points to a definition
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				_, found := definitions[strings.TrimPrefix(ref, "#/definitions/")]
+				assert.True(t, found, ref)
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(schema)
+
+	// the plugin of the type is chosen by `type`
+	branch := func(section string, pluginType string) map[string]any {
+		for _, condition := range definitions[section].(map[string]any)["allOf"].([]any) {
+			c := condition.(map[string]any)
+			if c["if"].(map[string]any)["properties"].(map[string]any)["type"].(map[string]any)["const"] == pluginType {
+				return c["then"].(map[string]any)
+			}
+		}
+
+		t.Fatalf("No branch for %v: %v", section, pluginType)
+		return nil
+	}
+
+	for _, tt := range []struct {
+		section string
+		types   []string
+	}{
+		{section: "input", types: []string{"sql", "dynamodb", "random", "exec"}},
+		{section: "output", types: []string{"bigquery", "file", "stdout", "exec"}},
+		{section: "transform", types: []string{"rename", "drop", "cast", "compute"}},
+	} {
+		for _, pluginType := range tt.types {
+			then := branch(tt.section, pluginType)
+			assert.Equal(t, false, then["additionalProperties"], pluginType)
+			assert.Contains(t, then["properties"], "type", pluginType)
+		}
+	}
+
+	dynamodb := branch("input", "dynamodb")["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "integer", "description": "Number of items in a page (default: 1000)"}, dynamodb["pageSize"])
+
+	sqlColumn := definitions["InputPluginSqlConfigSchemaColumn"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, []any{"string", "int", "float", "decimal", "bool", "date", "time", "json"}, sqlColumn["type"].(map[string]any)["enum"])
+
+	bigQueryColumn := definitions["OutputPluginBigQueryConfigSchemaColumn"].(map[string]any)["properties"].(map[string]any)
+	bigQueryType := bigQueryColumn["type"].(map[string]any)["anyOf"].([]any)
+	assert.Contains(t, bigQueryType[0].(map[string]any)["enum"], "record")
+	assert.Contains(t, bigQueryType[0].(map[string]any)["enum"], "int64")
+	pattern := regexp.MustCompile(bigQueryType[1].(map[string]any)["pattern"].(string))
+	for _, columnType := range []string{"RECORD", "record", "Integer", "INT64", "float64", "Bool"} {
+		assert.True(t, pattern.MatchString(columnType), columnType)
+		_, err := getType(columnType)
+		assert.NoError(t, err, columnType)
+	}
+	assert.False(t, pattern.MatchString("int"))
+	assert.Equal(t, map[string]any{"$ref": "#/definitions/OutputPluginBigQueryConfigSchemaColumn"}, bigQueryColumn["fields"].(map[string]any)["additionalProperties"])
+
+	cast := branch("transform", "cast")["properties"].(map[string]any)
+	assert.Equal(t, []any{"string", "int", "float", "bool", "time"}, cast["columns"].(map[string]any)["additionalProperties"].(map[string]any)["enum"])
+}
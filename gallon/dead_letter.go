@@ -0,0 +1,157 @@
+package gallon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// RecordError is an error which occurred while processing a specific record.
+// Plugins send it to the errs channel when they reject a record, so that the record can be sent to Gallon.DeadLetter.
+type RecordError struct {
+	// Record is the original record. It must be serializable with json.Marshal.
+	Record any
+	Err    error
+}
+
+// NewRecordError creates a RecordError. If the record is a GallonRecord, pass the pointer of it to keep the order of keys in JSON.
+func NewRecordError(record any, err error) *RecordError {
+	return &RecordError{
+		Record: record,
+		Err:    err,
+	}
+}
+
+func (e *RecordError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// DeadLetter is a record rejected in a migration.
+// It is sent to Gallon.DeadLetter as a GallonRecord which has `record`, `error`, `stage` and `timestamp` keys.
+type DeadLetter struct {
+	Record    any
+	Error     string
+	Stage     GallonStage
+	Timestamp time.Time
+}
+
+func newDeadLetter(stage GallonStage, record any, err error) DeadLetter {
+	return DeadLetter{
+		Record:    record,
+		Error:     err.Error(),
+		Stage:     stage,
+		Timestamp: time.Now(),
+	}
+}
+
+func (d DeadLetter) toGallonRecord() GallonRecord {
+	record := NewGallonRecord()
+	record.Set("record", d.Record)
+	record.Set("error", d.Error)
+	record.Set("stage", string(d.Stage))
+	record.Set("timestamp", d.Timestamp)
+
+	return record
+}
+
+type deadLetterJson struct {
+	Record    json.RawMessage `json:"record"`
+	Error     string          `json:"error"`
+	Stage     GallonStage     `json:"stage"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// ReadDeadLetters reads dead letters in JSONL format (e.g. written by the file output plugin with `format: jsonl`).
+// If a record is a JSON object, DeadLetter.Record will be a *GallonRecord.
+// The numbers in the records are json.Number not to lose the precision of large integers (See InputPluginDeadLetter for converting them).
+func ReadDeadLetters(r io.Reader) ([]DeadLetter, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	deadLetters := []DeadLetter{}
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var item deadLetterJson
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			return nil, fmt.Errorf("failed to parse dead letter at line %v: %w", line, err)
+		}
+
+		var record any
+		if trimmed := bytes.TrimSpace(item.Record); len(trimmed) > 0 && trimmed[0] == '{' {
+			r, err := decodeDeadLetterRecord(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse record at line %v: %w", line, err)
+			}
+
+			record = &r
+		} else if len(item.Record) > 0 {
+			decoder := json.NewDecoder(bytes.NewReader(item.Record))
+			decoder.UseNumber()
+			if err := decoder.Decode(&record); err != nil {
+				return nil, fmt.Errorf("failed to parse record at line %v: %w", line, err)
+			}
+		}
+
+		deadLetters = append(deadLetters, DeadLetter{
+			Record:    record,
+			Error:     item.Error,
+			Stage:     item.Stage,
+			Timestamp: item.Timestamp,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return deadLetters, nil
+}
+
+// decodeDeadLetterRecord decodes a JSON object into a GallonRecord in the order of the keys, with the numbers as json.Number.
+func decodeDeadLetterRecord(data []byte) (GallonRecord, error) {
+	record := NewGallonRecord()
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if _, err := decoder.Token(); err != nil {
+		return record, err
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return record, err
+		}
+
+		key, ok := token.(string)
+		if !ok {
+			return record, fmt.Errorf("invalid key: %v", token)
+		}
+
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return record, err
+		}
+
+		record.Set(key, value)
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return record, err
+	}
+
+	return record, nil
+}
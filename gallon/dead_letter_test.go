@@ -0,0 +1,136 @@
+package gallon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_read_dead_letters(t *testing.T) {
+	deadLetters, err := ReadDeadLetters(strings.NewReader(`{"record":{"id":"2","name":"foo","age":20},"error":"failed to deserialize","stage":"load","timestamp":"2024-01-01T00:00:00Z"}
+
+{"record":"map[id:{3}]","error":"failed to serialize","stage":"extract","timestamp":"2024-01-01T00:00:00Z"}
+`))
+	if err != nil {
+		t.Fatalf("Could not read dead letters: %s", err)
+	}
+
+	assert.Len(t, deadLetters, 2)
+
+	record, ok := deadLetters[0].Record.(*GallonRecord)
+	if !ok {
+		t.Fatalf("Expected *GallonRecord, got: %T", deadLetters[0].Record)
+	}
+	assert.Equal(t, []string{"id", "name", "age"}, record.Keys())
+	assert.Equal(t, GallonStageLoad, deadLetters[0].Stage)
+	assert.Equal(t, "failed to deserialize", deadLetters[0].Error)
+
+	assert.Equal(t, "map[id:{3}]", deadLetters[1].Record)
+	assert.Equal(t, GallonStageExtract, deadLetters[1].Stage)
+}
+
+func Test_replay_dead_letters(t *testing.T) {
+	deadLetters, err := ReadDeadLetters(strings.NewReader(`{"record":{"id":"1","name":"foo"},"error":"error","stage":"load","timestamp":"2024-01-01T00:00:00Z"}
+{"record":{"id":"2","name":"bar"},"error":"error","stage":"load","timestamp":"2024-01-01T00:00:00Z"}
+{"record":"map[id:{3}]","error":"error","stage":"extract","timestamp":"2024-01-01T00:00:00Z"}
+`))
+	if err != nil {
+		t.Fatalf("Could not read dead letters: %s", err)
+	}
+
+	output, err := NewOutputPluginFileFromConfig([]byte(`
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	buf := new(bytes.Buffer)
+	writer := bufio.NewWriter(buf)
+	output.newWriter = func() (io.WriteCloser, error) {
+		return NewNopWriteCloser(writer), nil
+	}
+
+	g := Gallon{
+		Logger: logger,
+		Input:  NewInputPluginDeadLetter(deadLetters, 1, nil),
+		Output: output,
+	}
+
+	result, err := g.RunWithResult(context.Background())
+	if err != nil {
+		t.Errorf("Could not run command: %s", err)
+	}
+
+	assert.Equal(t, 2, result.LoadedRecords)
+	assert.Equal(t, 1, result.RejectedRecords)
+
+	var recordErr *RecordError
+	assert.True(t, errors.As(result.Errors[0], &recordErr))
+	assert.Equal(t, "map[id:{3}]", recordErr.Record)
+
+	expected := `{"id":"1","name":"foo"}
+{"id":"2","name":"bar"}
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func Test_dead_letter_numbers(t *testing.T) {
+	deadLetters, err := ReadDeadLetters(strings.NewReader(`{"record":{"id":9007199254740993,"price":1.5,"amount":123,"meta":{"count":2},"tags":[1,2],"extra":3},"error":"error","stage":"load","timestamp":"2024-01-01T00:00:00Z"}
+`))
+	if err != nil {
+		t.Fatalf("Could not read dead letters: %s", err)
+	}
+
+	record := deadLetters[0].Record.(*GallonRecord)
+	id, _ := record.Get("id")
+	assert.Equal(t, json.Number("9007199254740993"), id)
+
+	tests := []struct {
+		name     string
+		schema   *GallonSchema
+		expected []any
+	}{
+		{
+			name: "schema",
+			schema: &GallonSchema{Fields: []GallonField{
+				{Name: "id", Type: GallonTypeInt},
+				{Name: "price", Type: GallonTypeFloat},
+				{Name: "amount", Type: GallonTypeNumber},
+				{Name: "meta", Type: GallonTypeObject, Fields: []GallonField{{Name: "count", Type: GallonTypeFloat}}},
+				{Name: "tags", Type: GallonTypeJSON},
+			}},
+			expected: []any{int64(9007199254740993), 1.5, "123", map[string]any{"count": 2.0}, []any{1.0, 2.0}, int64(3)},
+		},
+		{
+			name:     "no schema",
+			expected: []any{int64(9007199254740993), 1.5, int64(123), map[string]any{"count": int64(2)}, []any{int64(1), int64(2)}, int64(3)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := NewInputPluginDeadLetter(deadLetters, 10, tt.schema)
+			input.ReplaceLogger(logger)
+
+			messages := make(chan []GallonRecord, 10)
+			assert.NoError(t, input.Extract(context.Background(), messages, make(chan error, 10)))
+			close(messages)
+
+			records := <-messages
+			assert.Len(t, records, 1)
+			assert.Equal(t, []string{"id", "price", "amount", "meta", "tags", "extra"}, records[0].Keys())
+			assert.Equal(t, tt.expected, records[0].Values())
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package gallon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// The exec plugins run a command and exchange JSON lines (one JSON object per line) with it over stdin and stdout.
+// Every message has a `type`:
+//
+//   - init (gallon -> command): the first line of stdin. `config` is the `config` section of the plugin.
+//   - schema (both directions): optional, before any records. `columns` are the names and the types of the columns.
+//     The output command receives it when the schema of the records is known (See GallonSchema).
+//   - records (both directions): a batch of records as JSON objects.
+//   - error (command -> gallon): a non-fatal error with `message`, and the rejected `record` if any.
+//
+// The input command sends the records to stdout and exits. The output command reads the records from stdin until EOF and exits.
+// A non-zero exit status is a fatal error. stderr of the command is written to the log.
+type execMessage struct {
+	Type    string          `json:"type"`
+	Config  any             `json:"config,omitempty"`
+	Columns []ExecColumn    `json:"columns,omitempty"`
+	Records []*GallonRecord `json:"records,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Record  *GallonRecord   `json:"record,omitempty"`
+}
+
+// ExecColumn is a column in the schema message of an exec input command.
+type ExecColumn struct {
+	Name string `json:"name" yaml:"name"`
+	// Type is one of string, int, float, bool, time (RFC3339 string) and any. Defaults to any.
+	Type string `json:"type" yaml:"type"`
+}
+
+func (c ExecColumn) getValue(value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch c.Type {
+	case "", "any":
+		return value, nil
+	case "string":
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("value is not string: %v", value)
+		}
+
+		return v, nil
+	case "int":
+		switch v := value.(type) {
+		case float64:
+			if v != float64(int64(v)) {
+				return nil, fmt.Errorf("value is not int: %v", value)
+			}
+
+			return int64(v), nil
+		case string:
+			// large integers may be sent as strings, since JSON numbers are parsed as float64
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("value is not int: %v", value)
+			}
+
+			return i, nil
+		default:
+			return nil, fmt.Errorf("value is not int: %v", value)
+		}
+	case "float":
+		v, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("value is not float: %v", value)
+		}
+
+		return v, nil
+	case "bool":
+		v, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("value is not bool: %v", value)
+		}
+
+		return v, nil
+	case "time":
+		v, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("value is not time: %v", value)
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse time: %v", err)
+		}
+
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unknown column type: %v", c.Type)
+	}
+}
+
+// execColumns converts the schema into the columns of the schema message.
+func execColumns(schema GallonSchema) []ExecColumn {
+	columns := []ExecColumn{}
+	for _, f := range schema.Fields {
+		t := "any"
+		switch f.Type {
+		case GallonTypeString, GallonTypeDate, GallonTypeNumber:
+			t = "string"
+		case GallonTypeInt, GallonTypeFloat, GallonTypeBool, GallonTypeTime:
+			t = string(f.Type)
+		}
+
+		columns = append(columns, ExecColumn{Name: f.Name, Type: t})
+	}
+
+	return columns
+}
+
+// ExecConfig is the common config of the exec plugins.
+type ExecConfig struct {
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args"`
+	Env     map[string]string `yaml:"env"`
+	// Config is sent to the command in the init message.
+	Config map[string]any `yaml:"config"`
+}
+
+// newExecCommand creates the command. It is interrupted when ctx is cancelled, and killed if it does not exit in 10 seconds.
+func newExecCommand(ctx context.Context, logger logr.Logger, config ExecConfig) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, config.Command, config.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range config.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%v=%v", k, v))
+	}
+	cmd.Stderr = &execLogWriter{logger: logger}
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = 10 * time.Second
+
+	return cmd
+}
+
+// newExecError converts an error message of the command into an error.
+func newExecError(msg execMessage) error {
+	err := errors.New(msg.Message)
+	if msg.Record != nil {
+		return NewRecordError(msg.Record, err)
+	}
+
+	return err
+}
+
+// execLogWriter writes each line of stderr of the command to the log.
+type execLogWriter struct {
+	logger logr.Logger
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *execLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// keep the incomplete line for the next write
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			break
+		}
+
+		w.logger.Info(strings.TrimRight(line, "\r\n"), "stream", "stderr")
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,168 @@
+package gallon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_exec_input(t *testing.T) {
+	input, err := NewInputPluginExecFromConfig([]byte(`
+in:
+  type: exec
+  command: sh
+  args:
+    - -c
+    - |
+      read init
+      echo "$init" >&2
+      echo '{"type":"schema","columns":[{"name":"id","type":"int"},{"name":"name","type":"string"},{"name":"created_at","type":"time"}]}'
+      echo '{"type":"records","records":[{"name":"foo","id":1,"created_at":"2024-01-02T03:04:05Z"},{"id":2,"name":"bar"}]}'
+      echo '{"type":"error","message":"broken row","record":{"id":3}}'
+      echo '{"type":"records","records":[{"id":4.5,"name":"baz"}]}'
+  config:
+    table: users
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	output := &outputPluginFlushOnCancel{onLoad: func() {}}
+
+	g := Gallon{
+		Logger: logger,
+		Input:  input,
+		Output: output,
+	}
+
+	result, err := g.RunWithResult(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.ExtractedRecords)
+	assert.Equal(t, 2, result.RejectedRecords)
+	assert.ErrorContains(t, result.Errors[0], "broken row")
+	assert.ErrorContains(t, result.Errors[1], "value is not int: 4.5")
+}
+
+func Test_exec_input_records(t *testing.T) {
+	input := NewInputPluginExec(ExecConfig{
+		Command: "sh",
+		Args:    []string{"-c", `cat > /dev/null; echo '{"type":"schema","columns":[{"name":"id","type":"int"},{"name":"created_at","type":"time"}]}'; echo '{"type":"records","records":[{"created_at":"2024-01-02T03:04:05Z","id":1,"extra":true}]}'`},
+	})
+	input.ReplaceLogger(logger)
+
+	messages := make(chan []GallonRecord, 10)
+	errs := make(chan error, 10)
+	assert.NoError(t, input.Extract(context.Background(), messages, errs))
+	close(messages)
+
+	records := <-messages
+	assert.Len(t, records, 1)
+	assert.Equal(t, []string{"id", "created_at"}, records[0].Keys())
+	assert.Equal(t, []any{int64(1), time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, records[0].Values())
+}
+
+func Test_exec_input_failure(t *testing.T) {
+	input := NewInputPluginExec(ExecConfig{
+		Command: "sh",
+		Args:    []string{"-c", "echo failed >&2; exit 1"},
+	})
+	input.ReplaceLogger(logger)
+
+	err := input.Extract(context.Background(), make(chan []GallonRecord, 10), make(chan error, 10))
+	assert.ErrorContains(t, err, "command failed: sh")
+}
+
+func Test_exec_output(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+
+	output, err := NewOutputPluginExecFromConfig([]byte(`
+out:
+  type: exec
+  command: sh
+  args:
+    - -c
+    - |
+      cat > "$OUT_PATH"
+      echo '{"type":"error","message":"rejected","record":{"id":"2"}}'
+  env:
+    OUT_PATH: ` + path + `
+  config:
+    table: users
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	page := []GallonRecord{}
+	for _, id := range []string{"1", "2"} {
+		r := NewGallonRecord()
+		r.Set("id", id)
+
+		page = append(page, r)
+	}
+
+	g := Gallon{
+		Logger: logger,
+		Input:  NewInputPluginStub([][]GallonRecord{page, page[:1]}),
+		Output: output,
+	}
+
+	result, err := g.RunWithResult(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.LoadedRecords)
+	assert.Equal(t, 1, result.RejectedRecords)
+
+	bs, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"init","config":{"table":"users"}}
+{"type":"records","records":[{"id":"1"},{"id":"2"}]}
+{"type":"records","records":[{"id":"1"}]}
+`, string(bs))
+}
+
+func Test_exec_output_schema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.jsonl")
+
+	output, err := NewOutputPluginExecFromConfig([]byte(`
+out:
+  type: exec
+  command: sh
+  args:
+    - -c
+    - cat > "$OUT_PATH"
+  env:
+    OUT_PATH: ` + path + `
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	r := NewGallonRecord()
+	r.Set("id", "1")
+	r.Set("age", int64(20))
+
+	g := Gallon{
+		Logger: logger,
+		Input: &schemaInputPluginStub{
+			InputPluginStub: NewInputPluginStub([][]GallonRecord{{r}}),
+			schema: GallonSchema{Fields: []GallonField{
+				{Name: "id", Type: GallonTypeString},
+				{Name: "age", Type: GallonTypeInt, Nullable: true},
+			}},
+		},
+		Output: output,
+	}
+
+	assert.NoError(t, g.Run(context.Background()))
+
+	bs, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"init","config":null}
+{"type":"schema","columns":[{"name":"id","type":"string"},{"name":"age","type":"int"}]}
+{"type":"records","records":[{"id":"1","age":20}]}
+`, string(bs))
+}
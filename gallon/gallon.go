@@ -10,9 +10,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
 )
 
 type GallonRecord orderedmap.OrderedMap[string, any]
@@ -57,6 +64,13 @@ func (r *GallonRecord) MarshalJSON() ([]byte, error) {
 	return json.Marshal(r.asOrderdMap())
 }
 
+var _ json.Unmarshaler = &GallonRecord{}
+
+// UnmarshalJSON parses a JSON object keeping the order of keys. Nested objects are parsed as map[string]any.
+func (r *GallonRecord) UnmarshalJSON(data []byte) error {
+	return r.asOrderdMap().UnmarshalJSON(data)
+}
+
 type BasePlugin interface {
 	// Extract extracts data from the source and sends it to the messages channel.
 	// It is called in Gallon.Run() at the beginning.
@@ -73,6 +87,15 @@ type InputPlugin interface {
 	Extract(ctx context.Context, messages chan []GallonRecord, errs chan error) error
 }
 
+type TransformPlugin interface {
+	BasePlugin
+
+	// Transform transforms a batch of records sent from the input plugin (or the previous transform plugin).
+	// If a record cannot be transformed, send an error (See RecordError) to the errs channel and omit it from the returned records.
+	// If an error is returned, the migration will be cancelled.
+	Transform(ctx context.Context, records []GallonRecord, errs chan error) ([]GallonRecord, error)
+}
+
 type OutputPlugin interface {
 	BasePlugin
 
@@ -87,88 +110,736 @@ type Gallon struct {
 	Logger logr.Logger
 	Input  InputPlugin
 	Output OutputPlugin
+	// Transforms are applied in order to the records between Input and Output. (optional)
+	Transforms []TransformPlugin
+	// ErrorPolicy defines how many non-fatal errors are tolerated. See ErrorPolicy for the default.
+	ErrorPolicy ErrorPolicy
+	// DeadLetter receives the records rejected by the plugins (See RecordError) as DeadLetter records. (optional)
+	DeadLetter OutputPlugin
+	// Outputs are the additional outputs which receive the same records as Output. (optional)
+	Outputs []OutputPlugin
+	// OnOutputFailure defines whether a failing output aborts the other outputs. Defaults to OutputFailureAbort.
+	OnOutputFailure OutputFailurePolicy
+	// Checkpoint saves the cursor of the input plugin (See ResumableInputPlugin) after each batch is loaded. (optional)
+	// It is cleared when the migration has finished successfully. To resume a migration, call ResumableInputPlugin.Resume before Run.
+	Checkpoint CheckpointStore
+	// RateLimit limits the records and batches per second between Input and Output. (optional)
+	RateLimit RateLimit
+	// Batch re-chunks the batches sent to each output. See BatchPolicy. (optional)
+	Batch BatchPolicy
+	// Verify compares the number of records in the source and the outputs after the migration. (optional)
+	Verify *VerifyPolicy
+}
+
+// OutputFailurePolicy defines what happens to the other outputs when one of the outputs fails fatally.
+type OutputFailurePolicy string
+
+const (
+	// OutputFailureAbort cancels the whole migration when an output fails.
+	OutputFailureAbort OutputFailurePolicy = "abort"
+	// OutputFailureContinue lets the other outputs load the rest of the records.
+	// The migration still fails with a *GallonError after all the outputs have finished.
+	OutputFailureContinue OutputFailurePolicy = "continue"
+)
+
+// Validate returns an error if the policy is unknown. The empty policy is the same as OutputFailureAbort.
+func (p OutputFailurePolicy) Validate() error {
+	switch p {
+	case "", OutputFailureAbort, OutputFailureContinue:
+		return nil
+	default:
+		return fmt.Errorf("unknown onOutputFailure: %v", p)
+	}
+}
+
+// outputs returns Output followed by Outputs.
+func (g *Gallon) outputs() []OutputPlugin {
+	outputs := []OutputPlugin{}
+	if g.Output != nil {
+		outputs = append(outputs, g.Output)
+	}
+
+	return append(outputs, g.Outputs...)
+}
+
+// batch is a batch of records numbered in the order of extraction.
+type batch struct {
+	seq     int
+	records []GallonRecord
+}
+
+// outputError is an error sent from the output plugin at index.
+type outputError struct {
+	index int
+	err   error
+}
+
+func outputErr(index int, err error) error {
+	if index == 0 {
+		return err
+	}
+
+	return fmt.Errorf("output %v: %w", index, err)
 }
 
 // Run starts goroutines for extract and load, and waits for them to finish.
 //
-// If too many errors are occurred, it will cancel the context and return ErrTooManyErrors.
+// If too many errors are occurred (See ErrorPolicy), it will cancel the context and return ErrTooManyErrors.
+// If Extract or Load returns an error, it will cancel the context and return a *GallonError which wraps the error.
+// If ctx is cancelled (e.g. on SIGINT), it waits for the plugins to stop and returns the cause of ctx (See context.Cause).
 func (g *Gallon) Run(ctx context.Context) error {
+	_, err := g.RunWithResult(ctx)
+	return err
+}
+
+// RunResult is the statistics of a migration returned by Gallon.RunWithResult.
+type RunResult struct {
+	// ExtractedRecords is the number of records sent from the input plugin to the output plugin.
+	ExtractedRecords int
+	// ExtractedBatches is the number of batches sent from the input plugin to the output plugin.
+	ExtractedBatches int
+	// LoadedRecords is the number of records sent to the output plugin (after transforms) which are not rejected by it.
+	// If there are multiple outputs, it is the smallest number among them.
+	LoadedRecords int
+	// RejectedRecords is the number of non-fatal errors reported by the plugins.
+	RejectedRecords int
+	// Errors is the list of non-fatal errors reported by the plugins via the errs channel.
+	// Since each error may hold the rejected record (See RecordError), only the first 100 (MaxResultErrors) errors are kept as samples.
+	// Use RejectedRecords for the number of all the errors, and Gallon.DeadLetter to keep all the rejected records.
+	Errors []error
+	// DeadLetterRecords is the number of rejected records sent to Gallon.DeadLetter.
+	DeadLetterRecords int
+
+	// Outputs is the statistics of each output, in the order of Gallon.Output and Gallon.Outputs.
+	Outputs []OutputResult
+	// Verification is the result of Gallon.Verify, if it is set.
+	Verification *Verification
+
+	ExtractDuration time.Duration
+	LoadDuration    time.Duration
+	Duration        time.Duration
+}
+
+// MaxResultErrors is the maximum number of errors kept in RunResult.Errors.
+const MaxResultErrors = 100
+
+// OutputResult is the statistics of an output plugin in a migration.
+type OutputResult struct {
+	// LoadedRecords is the number of records sent to the output which are not rejected by it.
+	LoadedRecords int
+	// RejectedRecords is the number of errors reported by the output.
+	RejectedRecords int
+	// Err is the fatal error returned by the output, if any.
+	Err      error
+	Duration time.Duration
+
+	sentRecords int
+}
+
+// RunWithResult is the same as Run, but it also returns the statistics of the migration.
+// The result is returned even if the migration fails.
+func (g *Gallon) RunWithResult(ctx context.Context) (*RunResult, error) {
+	inputPlugin, inputTable := metricLabelsOf(g.Input)
+	ctx, span := tracer.Start(ctx, "gallon.Run", trace.WithAttributes(
+		attribute.String("gallon.input.plugin", inputPlugin),
+		attribute.String("gallon.input.table", inputTable),
+		attribute.Int("gallon.outputs", len(g.outputs())),
+	))
+
+	result, err := g.runWithResult(ctx)
+
+	span.SetAttributes(
+		attribute.Int("gallon.extracted_records", result.ExtractedRecords),
+		attribute.Int("gallon.loaded_records", result.LoadedRecords),
+		attribute.Int("gallon.rejected_records", result.RejectedRecords),
+	)
+	endSpan(span, err)
+
+	return result, err
+}
+
+func (g *Gallon) runWithResult(ctx context.Context) (*RunResult, error) {
 	g.Input.ReplaceLogger(g.Logger)
-	g.Output.ReplaceLogger(g.Logger)
+	outputs := g.outputs()
+	for i, output := range outputs {
+		if len(outputs) > 1 {
+			output.ReplaceLogger(g.Logger.WithValues("output", i))
+		} else {
+			output.ReplaceLogger(g.Logger)
+		}
+	}
+	for _, transform := range g.Transforms {
+		transform.ReplaceLogger(g.Logger)
+	}
 
-	messages := make(chan []GallonRecord)
+	if schema := g.schema(); schema != nil {
+		for _, output := range outputs {
+			if output, ok := output.(SchemaOutputPlugin); ok {
+				output.SetSchema(*schema)
+			}
+		}
+	}
+
+	startedAt := time.Now()
+
+	// backlog is the number of batches which are extracted but not yet sent to all the outputs
+	backlog := newBacklogMetric(g.Input)
+	defer backlog.close()
+
+	// extracted records are relayed to the transforms and then to messages, so that the records can be counted
+	// and numbered for checkpoints
+	extracted := make(chan []GallonRecord)
+	relayed := make(chan batch)
 
-	errs := make(chan error, 10)
-	tooManyErrorsLimit := 50
+	extractErrs := make(chan error, 10)
+	transformErrs := make(chan error, 10)
+	loadErrs := make(chan outputError, 10)
+	maxErrors, hasMaxErrors := g.ErrorPolicy.maxErrors()
+
+	var mu sync.Mutex
+	result := RunResult{}
+	extractRejected := 0
+	outputResults := make([]OutputResult, len(outputs))
+
+	// the dead-letter output runs with the parent context, since it has to receive the errors after the migration
+	parentCtx := ctx
+
+	batchers := make([]*outputBatcher, len(outputs))
+	for i := range outputs {
+		batchers[i] = newOutputBatcher(g.Batch)
+	}
+
+	var checkpoints *checkpointer
+	if g.Checkpoint != nil {
+		checkpoints = newCheckpointer(g.Logger, g.Checkpoint, len(outputs))
+
+		if input, ok := g.Input.(ResumableInputPlugin); ok {
+			input.SetCursorHandler(checkpoints.reportCursor)
+		} else {
+			g.Logger.Info("the input plugin does not support checkpoints")
+		}
+
+		for i, output := range outputs {
+			if output, ok := output.(AckOutputPlugin); ok {
+				output.SetAckHandler(func() {
+					// a re-chunked batch may complete some (or none) of the extracted batches
+					for range batchers[i].acked() {
+						checkpoints.ack(parentCtx, i)
+					}
+				})
+			}
+		}
+	}
 
 	ctx, cancel := context.WithCancelCause(ctx)
 	defer cancel(nil)
 
+	var deadLetters chan []GallonRecord
+	var deadLetterErr error
+	deadLetterDone := make(chan struct{})
+
+	if g.DeadLetter != nil {
+		g.DeadLetter.ReplaceLogger(g.Logger.WithName("deadLetter"))
+
+		deadLetters = make(chan []GallonRecord)
+		deadLetterErrs := make(chan error, 10)
+
+		go func() {
+			for err := range deadLetterErrs {
+				g.Logger.Error(err, "error in dead letter")
+			}
+		}()
+
+		go func() {
+			defer func() {
+				close(deadLetterErrs)
+				close(deadLetterDone)
+			}()
+
+			if err := g.DeadLetter.Load(withDeadLetter(parentCtx), deadLetters, deadLetterErrs); err != nil {
+				g.Logger.Error(err, "failed to load dead letters")
+				deadLetterErr = err
+			}
+		}()
+	} else {
+		close(deadLetterDone)
+	}
+
+	extractDone := make(chan struct{})
 	go func(ctx context.Context) {
 		defer func() {
 			g.Logger.Info("end extract")
 
-			defer close(messages)
+			close(extracted)
+			close(extractDone)
 		}()
 
 		g.Logger.Info("start extract")
 
-		if err := g.Input.Extract(ctx, messages, errs); err != nil {
+		extractStartedAt := time.Now()
+		err := g.Input.Extract(ctx, extracted, extractErrs)
+
+		mu.Lock()
+		result.ExtractDuration = time.Since(extractStartedAt)
+		mu.Unlock()
+
+		if err != nil {
 			g.Logger.Error(err, "failed to extract")
+			cancel(&GallonError{Stage: GallonStageExtract, Err: err})
 		}
 	}(ctx)
 
+	go func(ctx context.Context) {
+		defer close(relayed)
+
+		limiter := newRateLimiter(g.RateLimit)
+
+		seq := 0
+		for msgs := range extracted {
+			if err := limiter.wait(ctx, len(msgs)); err != nil || ctx.Err() != nil {
+				// discard the batches sent after the cancellation, so that the input plugin is not blocked
+				continue
+			}
+
+			checkpoints.extracted(seq)
+			metricExtractedRecords.WithLabelValues(metricLabelsOf(g.Input)).Add(float64(len(msgs)))
+			backlog.add(1)
+
+			select {
+			case <-ctx.Done():
+			case relayed <- batch{seq: seq, records: msgs}:
+				seq++
+
+				mu.Lock()
+				result.ExtractedRecords += len(msgs)
+				result.ExtractedBatches++
+				mu.Unlock()
+			}
+		}
+	}(ctx)
+
+	messages := relayed
+	for _, transform := range g.Transforms {
+		transformed := make(chan batch)
+
+		go func(ctx context.Context, source chan batch) {
+			defer close(transformed)
+
+			for b := range source {
+				transformStartedAt := time.Now()
+				records, err := transform.Transform(ctx, b.records, transformErrs)
+				observeBatchDuration(GallonStageTransform, transform, transformStartedAt)
+				if err != nil {
+					g.Logger.Error(err, "failed to transform")
+					cancel(&GallonError{Stage: GallonStageTransform, Err: err})
+					return
+				}
+
+				if len(records) == 0 {
+					checkpoints.skip(parentCtx, b.seq)
+					backlog.add(-1)
+					continue
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case transformed <- batch{seq: b.seq, records: records}:
+				}
+			}
+		}(ctx, messages)
+
+		messages = transformed
+	}
+
+	stopErrors := make(chan struct{})
+	errorsDone := make(chan struct{})
+
+	loadDone := make(chan struct{})
 	go func(ctx context.Context) {
 		defer func() {
 			g.Logger.Info("end load")
 
 			cancel(nil)
+			close(loadDone)
 		}()
 
 		g.Logger.Info("start load")
 
-		if err := g.Output.Load(ctx, messages, errs); err != nil {
-			g.Logger.Error(err, "failed to load")
+		loadStartedAt := time.Now()
+
+		// every batch is broadcast to the outputs, each of which has its own channel
+		channels := make([]chan []GallonRecord, len(outputs))
+		loaded := make([]chan struct{}, len(outputs))
+		var wg sync.WaitGroup
+
+		for i, output := range outputs {
+			channels[i] = make(chan []GallonRecord)
+			loaded[i] = make(chan struct{})
+
+			outputErrs := make(chan error, 10)
+			forwarded := make(chan struct{})
+
+			// errors are tagged with the index of the output for the per-output accounting
+			go func() {
+				defer close(forwarded)
+
+				for err := range outputErrs {
+					select {
+					case loadErrs <- outputError{index: i, err: err}:
+					case <-errorsDone:
+					}
+				}
+			}()
+
+			received := channels[i]
+			if batchers[i] != nil {
+				rechunked := make(chan []GallonRecord)
+				go batchers[i].run(ctx, channels[i], rechunked, loaded[i])
+
+				received = rechunked
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer close(loaded[i])
+
+				outputStartedAt := time.Now()
+				err := output.Load(ctx, received, outputErrs)
+
+				close(outputErrs)
+				<-forwarded
+
+				mu.Lock()
+				outputResults[i].Duration = time.Since(outputStartedAt)
+				outputResults[i].Err = err
+				mu.Unlock()
+
+				if err != nil {
+					g.Logger.Error(err, "failed to load", "output", i)
+
+					if g.OnOutputFailure != OutputFailureContinue {
+						cancel(&GallonError{Stage: GallonStageLoad, Err: outputErr(i, err)})
+					}
+				}
+			}()
+		}
+
+	broadcast:
+		for b := range messages {
+			checkpoints.broadcasting(b.seq, len(outputs))
+
+			for i := range outputs {
+				checkpoints.sending(i, b.seq)
+
+				select {
+				case <-ctx.Done():
+					break broadcast
+				case <-loaded[i]:
+					// the output has failed, so the other outputs continue without it
+				case channels[i] <- b.records:
+					mu.Lock()
+					outputResults[i].sentRecords += len(b.records)
+					mu.Unlock()
+				}
+			}
+
+			backlog.add(-1)
+		}
+
+		for _, ch := range channels {
+			close(ch)
+		}
+		wg.Wait()
+
+		mu.Lock()
+		result.LoadDuration = time.Since(loadStartedAt)
+		var failed error
+		for i, r := range outputResults {
+			if r.Err != nil {
+				failed = outputErr(i, r.Err)
+				break
+			}
+		}
+		mu.Unlock()
+
+		if failed != nil {
+			cancel(&GallonError{Stage: GallonStageLoad, Err: failed})
 		}
 	}(ctx)
 
 	go func() {
+		defer close(errorsDone)
+
 		errorCount := 0
+		exceededMaxPercentage := false
+		// plugin is used for the metrics, which is nil for the transforms since they share the errs channel
+		handle := func(stage GallonStage, plugin any, err error) {
+			if err == nil {
+				return
+			}
 
-		for {
-			select {
-			case err := <-errs:
-				if err != nil {
-					errorCount++
-					g.Logger.Error(err, "error in gallon", "errorCount", errorCount)
+			pluginName, table := string(stage), ""
+			if plugin != nil {
+				pluginName, table = metricLabelsOf(plugin)
+			}
+			metricFailedRecords.WithLabelValues(string(stage), pluginName, table).Inc()
+
+			errorCount++
+			g.Logger.Error(err, "error in gallon", "errorCount", errorCount, "stage", stage)
+
+			mu.Lock()
+			if len(result.Errors) < MaxResultErrors {
+				result.Errors = append(result.Errors, err)
+			}
+			result.RejectedRecords++
+			switch stage {
+			case GallonStageExtract:
+				extractRejected++
+			}
+			rejected, processed := result.RejectedRecords, result.ExtractedRecords+extractRejected
+			mu.Unlock()
+
+			var recordErr *RecordError
+			if deadLetters != nil && errors.As(err, &recordErr) {
+				deadLetter := newDeadLetter(stage, recordErr.Record, err)
+
+				select {
+				case deadLetters <- []GallonRecord{deadLetter.toGallonRecord()}:
+					metricDeadLetterRecords.WithLabelValues(string(stage)).Inc()
+
+					mu.Lock()
+					result.DeadLetterRecords++
+					mu.Unlock()
+				case <-deadLetterDone:
 				}
+			}
 
-				if errorCount > tooManyErrorsLimit {
-					cancel(ErrTooManyErrors)
-					g.Logger.Error(ErrTooManyErrors, "quit", "errorCount", errorCount)
-					return
+			if g.ErrorPolicy.FailFast {
+				cancel(&GallonError{Stage: stage, Err: err})
+				return
+			}
+
+			if hasMaxErrors && errorCount == maxErrors+1 {
+				cancel(ErrTooManyErrors)
+				g.Logger.Error(ErrTooManyErrors, "quit", "errorCount", errorCount)
+				return
+			}
+
+			if !exceededMaxPercentage && g.ErrorPolicy.exceedsMaxPercentageWhileRunning(rejected, processed) {
+				exceededMaxPercentage = true
+				cancel(ErrTooManyErrors)
+				g.Logger.Error(ErrTooManyErrors, "quit", "rejected", rejected, "processed", processed)
+			}
+		}
+
+		handleOutput := func(e outputError) {
+			if e.err == nil {
+				return
+			}
+
+			mu.Lock()
+			outputResults[e.index].RejectedRecords++
+			mu.Unlock()
+
+			handle(GallonStageLoad, outputs[e.index], e.err)
+		}
+
+		for {
+			select {
+			case err := <-extractErrs:
+				handle(GallonStageExtract, g.Input, err)
+			case err := <-transformErrs:
+				handle(GallonStageTransform, nil, err)
+			case e := <-loadErrs:
+				handleOutput(e)
+			case <-stopErrors:
+				// drain the errors which are sent before the migration has finished
+				for {
+					select {
+					case err := <-extractErrs:
+						handle(GallonStageExtract, g.Input, err)
+					case err := <-transformErrs:
+						handle(GallonStageTransform, nil, err)
+					case e := <-loadErrs:
+						handleOutput(e)
+					default:
+						return
+					}
 				}
 			}
 		}
 	}()
 
-	for {
-		select {
-		case <-ctx.Done():
-			if context.Cause(ctx) == ErrTooManyErrors {
-				return ErrTooManyErrors
-			}
+	<-ctx.Done()
 
-			return nil
+	// wait for the plugins to stop, so that the outputs can flush the loaded records and clean up
+	<-extractDone
+	<-loadDone
+
+	close(stopErrors)
+	<-errorsDone
+
+	if deadLetters != nil {
+		close(deadLetters)
+	}
+	<-deadLetterDone
+
+	mu.Lock()
+	snapshot := result
+	snapshot.Errors = slices.Clone(result.Errors)
+	snapshot.Outputs = make([]OutputResult, len(outputResults))
+	for i, r := range outputResults {
+		r.LoadedRecords = max(r.sentRecords-r.RejectedRecords, 0)
+		snapshot.Outputs[i] = r
+
+		if i == 0 || r.LoadedRecords < snapshot.LoadedRecords {
+			snapshot.LoadedRecords = r.LoadedRecords
 		}
 	}
+	snapshot.Duration = time.Since(startedAt)
+	processedRecords := result.ExtractedRecords + extractRejected
+	mu.Unlock()
+
+	cause := context.Cause(ctx)
+	if cause == ErrTooManyErrors {
+		return &snapshot, ErrTooManyErrors
+	}
+
+	var gallonErr *GallonError
+	if errors.As(cause, &gallonErr) {
+		return &snapshot, gallonErr
+	}
+
+	if parentCtx.Err() != nil {
+		return &snapshot, context.Cause(parentCtx)
+	}
+
+	if deadLetterErr != nil {
+		return &snapshot, &GallonError{Stage: GallonStageDeadLetter, Err: deadLetterErr}
+	}
+
+	if g.ErrorPolicy.exceedsMaxPercentage(snapshot.RejectedRecords, processedRecords) {
+		g.Logger.Error(ErrTooManyErrors, "rejected records exceed the limit", "rejected", snapshot.RejectedRecords, "processed", processedRecords)
+		return &snapshot, ErrTooManyErrors
+	}
+
+	if g.Checkpoint != nil {
+		if err := g.Checkpoint.Clear(parentCtx); err != nil {
+			g.Logger.Error(err, "failed to clear checkpoint")
+		}
+	}
+
+	if g.Verify != nil {
+		verification, err := g.verify(parentCtx)
+		snapshot.Verification = verification
+		if err != nil {
+			return &snapshot, err
+		}
+	}
+
+	return &snapshot, nil
 }
 
 var ErrTooManyErrors = errors.New("too many errors")
 
+const defaultMaxErrors = 50
+
+// ErrorPolicy defines how many non-fatal errors (errors sent to the errs channel) are tolerated in a migration.
+// By default, a migration fails with ErrTooManyErrors when more than 50 errors are occurred.
+type ErrorPolicy struct {
+	// Max is the maximum number of errors. If the number of errors exceeds it, the migration is cancelled.
+	// If it is not set, 50 is used unless MaxPercentage is set.
+	Max *int `yaml:"max"`
+	// MaxPercentage is the maximum percentage (0-100) of rejected records to the processed records.
+	// Since the total number of records is unknown until the end, it is checked after the migration has finished
+	// and the migration fails with ErrTooManyErrors, without rolling back the loaded records.
+	// While the migration is running, it is cancelled if more than 50 records are rejected and the percentage is exceeded
+	// (e.g. an input which keeps failing), since the percentage of the first few records is unstable.
+	MaxPercentage *float64 `yaml:"maxPercentage"`
+	// FailFast cancels the migration on the first error, and returns a *GallonError which wraps it.
+	FailFast bool `yaml:"failFast"`
+}
+
+func (p ErrorPolicy) maxErrors() (int, bool) {
+	if p.Max != nil {
+		return *p.Max, true
+	}
+
+	if p.MaxPercentage != nil {
+		return 0, false
+	}
+
+	return defaultMaxErrors, true
+}
+
+func (p ErrorPolicy) exceedsMaxPercentage(rejected int, processed int) bool {
+	if p.MaxPercentage == nil || rejected == 0 {
+		return false
+	}
+
+	if processed == 0 {
+		return true
+	}
+
+	return float64(rejected)/float64(processed)*100 > *p.MaxPercentage
+}
+
+// exceedsMaxPercentageWhileRunning checks MaxPercentage with the records processed so far.
+// The default limit of the number of errors is used as the minimum, so that a few errors at the beginning do not cancel the migration.
+func (p ErrorPolicy) exceedsMaxPercentageWhileRunning(rejected int, processed int) bool {
+	return rejected > defaultMaxErrors && p.exceedsMaxPercentage(rejected, processed)
+}
+
+// GallonStage is a stage of a migration in which an error occurred.
+type GallonStage string
+
+const (
+	GallonStageExtract   GallonStage = "extract"
+	GallonStageTransform GallonStage = "transform"
+	GallonStageLoad      GallonStage = "load"
+	// GallonStageDeadLetter is the stage of sending rejected records to Gallon.DeadLetter
+	GallonStageDeadLetter GallonStage = "deadLetter"
+	// GallonStageVerify is the stage of counting the records for Gallon.Verify
+	GallonStageVerify GallonStage = "verify"
+)
+
+// GallonError is returned by Gallon.Run when a stage fails fatally.
+// Use errors.As to get the stage, and errors.Unwrap (or errors.Is) to inspect the cause.
+type GallonError struct {
+	Stage GallonStage
+	Err   error
+}
+
+func (e *GallonError) Error() string {
+	return fmt.Sprintf("failed to %v: %v", e.Stage, e.Err)
+}
+
+func (e *GallonError) Unwrap() error {
+	return e.Err
+}
+
 // GallonConfig is the schema of gallon config yaml.
 // Both `in` and `out` must contain `type` field. Plugins for input/output will be chosen by `type` field
+//
+// `deadLetter` is an optional output plugin config (in the same format as `out`) which receives the rejected records.
+// `transforms` is an optional list of transform plugin configs, each of which must contain `type` field.
+// `outs` is an optional list of output plugin configs which receive the same records as `out`.
+// `onOutputFailure` is either `abort` (default) or `continue` (See OutputFailurePolicy).
+// `checkpoint` is an optional config of the store of checkpoints (See CheckpointConfig).
+// `batch` is an optional policy to re-chunk the batches for the outputs (See BatchPolicy).
+// `verify` is an optional policy to compare the number of records after the migration (See VerifyPolicy).
 type GallonConfig[InConfig any, OutConfig any] struct {
-	In  InConfig  `yaml:"in"`
-	Out OutConfig `yaml:"out"`
+	In              InConfig            `yaml:"in"`
+	Out             OutConfig           `yaml:"out"`
+	Outs            []yaml.Node         `yaml:"outs"`
+	OnOutputFailure OutputFailurePolicy `yaml:"onOutputFailure"`
+	Errors          ErrorPolicy         `yaml:"errors"`
+	DeadLetter      yaml.Node           `yaml:"deadLetter"`
+	Transforms      []yaml.Node         `yaml:"transforms"`
+	Checkpoint      *CheckpointConfig   `yaml:"checkpoint"`
+	RateLimit       RateLimit           `yaml:"rateLimit"`
+	Batch           BatchPolicy         `yaml:"batch"`
+	Verify          *VerifyPolicy       `yaml:"verify"`
 }
@@ -1,9 +1,18 @@
 package gallon
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
 )
 
 func Test_too_many_errors(t *testing.T) {
@@ -46,3 +55,661 @@ format: json
 		t.Errorf("Could not run command: %s", err)
 	}
 }
+
+func Test_extract_error(t *testing.T) {
+	output, err := NewOutputPluginStdoutFromConfig([]byte(`
+format: json
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	extractErr := errors.New("prepare failed")
+
+	input := NewInputPluginStub([][]GallonRecord{})
+	input.extractErr = extractErr
+
+	g := Gallon{
+		Logger: logger,
+		Input:  input,
+		Output: output,
+	}
+
+	err = g.Run(context.Background())
+
+	var gallonErr *GallonError
+	if !errors.As(err, &gallonErr) {
+		t.Fatalf("Expected GallonError, got: %v", err)
+	}
+	if gallonErr.Stage != GallonStageExtract {
+		t.Errorf("Expected stage %v, got: %v", GallonStageExtract, gallonErr.Stage)
+	}
+	if !errors.Is(err, extractErr) {
+		t.Errorf("Expected wrapped error %v, got: %v", extractErr, err)
+	}
+}
+
+func Test_load_error(t *testing.T) {
+	output, err := NewOutputPluginFileFromConfig([]byte(`
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	loadErr := errors.New("load job failed")
+	output.newWriter = func() (io.WriteCloser, error) {
+		return nil, loadErr
+	}
+
+	r := NewGallonRecord()
+	r.Set("id", "1")
+
+	g := Gallon{
+		Logger: logger,
+		Input:  NewInputPluginStub([][]GallonRecord{{r}}),
+		Output: output,
+	}
+
+	err = g.Run(context.Background())
+
+	var gallonErr *GallonError
+	if !errors.As(err, &gallonErr) {
+		t.Fatalf("Expected GallonError, got: %v", err)
+	}
+	if gallonErr.Stage != GallonStageLoad {
+		t.Errorf("Expected stage %v, got: %v", GallonStageLoad, gallonErr.Stage)
+	}
+	if !errors.Is(err, loadErr) {
+		t.Errorf("Expected wrapped error %v, got: %v", loadErr, err)
+	}
+}
+
+func Test_run_result(t *testing.T) {
+	output, err := NewOutputPluginStdoutFromConfig([]byte(`
+format: json
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	output.deserialize = func(i GallonRecord) ([]byte, error) {
+		id, _ := i.Get("id")
+		if id == "rejected" {
+			return nil, errors.New("error")
+		}
+
+		return json.Marshal(&i)
+	}
+
+	data := [][]GallonRecord{}
+	for i := 0; i < 3; i++ {
+		page := []GallonRecord{}
+		for j := 0; j < 5; j++ {
+			r := NewGallonRecord()
+			if i == 1 && j == 2 {
+				r.Set("id", "rejected")
+			} else {
+				r.Set("id", fmt.Sprintf("%v-%v", i, j))
+			}
+
+			page = append(page, r)
+		}
+
+		data = append(data, page)
+	}
+
+	g := Gallon{
+		Logger: logger,
+		Input:  NewInputPluginStub(data),
+		Output: output,
+	}
+
+	result, err := g.RunWithResult(context.Background())
+	if err != nil {
+		t.Errorf("Could not run command: %s", err)
+	}
+
+	assert.Equal(t, 15, result.ExtractedRecords)
+	assert.Equal(t, 3, result.ExtractedBatches)
+	assert.Equal(t, 14, result.LoadedRecords)
+	assert.Equal(t, 1, result.RejectedRecords)
+	assert.Len(t, result.Errors, 1)
+}
+
+func Test_run_result_errors_are_capped(t *testing.T) {
+	output, err := NewOutputPluginStdoutFromConfig([]byte(`
+format: json
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	output.deserialize = func(i GallonRecord) ([]byte, error) {
+		return nil, errors.New("error")
+	}
+
+	data := [][]GallonRecord{}
+	for i := 0; i < 15; i++ {
+		page := []GallonRecord{}
+		for j := 0; j < 10; j++ {
+			r := NewGallonRecord()
+			r.Set("id", fmt.Sprintf("%v-%v", i, j))
+
+			page = append(page, r)
+		}
+
+		data = append(data, page)
+	}
+
+	maxErrors := 150
+	g := Gallon{
+		Logger:      logger,
+		Input:       NewInputPluginStub(data),
+		Output:      output,
+		ErrorPolicy: ErrorPolicy{Max: &maxErrors},
+	}
+
+	result, err := g.RunWithResult(context.Background())
+	if err != nil {
+		t.Errorf("Could not run command: %s", err)
+	}
+
+	assert.Equal(t, 150, result.RejectedRecords)
+	assert.Len(t, result.Errors, MaxResultErrors)
+}
+
+func Test_error_policy(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	floatPtr := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name        string
+		policy      ErrorPolicy
+		rejected    int
+		wantErr     error
+		wantFailure bool
+	}{
+		{
+			name:     "default limit is not exceeded",
+			policy:   ErrorPolicy{},
+			rejected: 10,
+		},
+		{
+			name:     "max is exceeded",
+			policy:   ErrorPolicy{Max: intPtr(5)},
+			rejected: 10,
+			wantErr:  ErrTooManyErrors,
+		},
+		{
+			name:     "max percentage is not exceeded",
+			policy:   ErrorPolicy{MaxPercentage: floatPtr(20)},
+			rejected: 10,
+		},
+		{
+			name:     "max percentage is exceeded",
+			policy:   ErrorPolicy{MaxPercentage: floatPtr(5)},
+			rejected: 10,
+			wantErr:  ErrTooManyErrors,
+		},
+		{
+			name:        "fail fast",
+			policy:      ErrorPolicy{FailFast: true},
+			rejected:    1,
+			wantFailure: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := NewOutputPluginStdoutFromConfig([]byte(`
+format: json
+`))
+			if err != nil {
+				t.Errorf("Could not create plugin: %s", err)
+			}
+
+			output.deserialize = func(i GallonRecord) ([]byte, error) {
+				id, _ := i.Get("id")
+				if id == "rejected" {
+					return nil, errors.New("error")
+				}
+
+				return json.Marshal(&i)
+			}
+
+			data := [][]GallonRecord{}
+			for i := 0; i < 10; i++ {
+				page := []GallonRecord{}
+				for j := 0; j < 10; j++ {
+					r := NewGallonRecord()
+					if i*10+j < tt.rejected {
+						r.Set("id", "rejected")
+					} else {
+						r.Set("id", fmt.Sprintf("%v-%v", i, j))
+					}
+
+					page = append(page, r)
+				}
+
+				data = append(data, page)
+			}
+
+			g := Gallon{
+				Logger:      logger,
+				Input:       NewInputPluginStub(data),
+				Output:      output,
+				ErrorPolicy: tt.policy,
+			}
+
+			err = g.Run(context.Background())
+			if tt.wantFailure {
+				var gallonErr *GallonError
+				if !errors.As(err, &gallonErr) {
+					t.Fatalf("Expected GallonError, got: %v", err)
+				}
+				assert.Equal(t, GallonStageLoad, gallonErr.Stage)
+				return
+			}
+
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
+
+// failingInputPlugin reports an error for every page until it is cancelled, like an input which retries a failing page.
+type failingInputPlugin struct {
+	InputPluginStub
+}
+
+func (i failingInputPlugin) Extract(
+	ctx context.Context,
+	messages chan []GallonRecord,
+	errs chan error,
+) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case errs <- errors.New("error"):
+		}
+	}
+}
+
+func Test_max_percentage_while_running(t *testing.T) {
+	output, err := NewOutputPluginStdoutFromConfig([]byte(`
+format: json
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	maxPercentage := 10.0
+	g := Gallon{
+		Logger:      logger,
+		Input:       failingInputPlugin{},
+		Output:      output,
+		ErrorPolicy: ErrorPolicy{MaxPercentage: &maxPercentage},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		result, err := g.RunWithResult(context.Background())
+		assert.Equal(t, ErrTooManyErrors, err)
+		assert.Greater(t, result.RejectedRecords, defaultMaxErrors)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the migration was not cancelled")
+	}
+}
+
+func Test_dead_letter(t *testing.T) {
+	output, err := NewOutputPluginStdoutFromConfig([]byte(`
+format: json
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	output.deserialize = func(i GallonRecord) ([]byte, error) {
+		id, _ := i.Get("id")
+		if id == "2" {
+			return nil, errors.New("error")
+		}
+
+		return json.Marshal(&i)
+	}
+
+	deadLetter, err := NewOutputPluginFileFromConfig([]byte(`
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	buf := new(bytes.Buffer)
+	writer := bufio.NewWriter(buf)
+	deadLetter.newWriter = func() (io.WriteCloser, error) {
+		return NewNopWriteCloser(writer), nil
+	}
+
+	page := []GallonRecord{}
+	for _, id := range []string{"1", "2", "3"} {
+		r := NewGallonRecord()
+		r.Set("id", id)
+		r.Set("name", "foo")
+
+		page = append(page, r)
+	}
+
+	g := Gallon{
+		Logger:     logger,
+		Input:      NewInputPluginStub([][]GallonRecord{page}),
+		Output:     output,
+		DeadLetter: deadLetter,
+	}
+
+	result, err := g.RunWithResult(context.Background())
+	if err != nil {
+		t.Errorf("Could not run command: %s", err)
+	}
+
+	assert.Equal(t, 1, result.DeadLetterRecords)
+
+	var deadLetterRecord struct {
+		Record    map[string]any `json:"record"`
+		Error     string         `json:"error"`
+		Stage     string         `json:"stage"`
+		Timestamp string         `json:"timestamp"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &deadLetterRecord); err != nil {
+		t.Fatalf("Could not parse dead letter: %s (%v)", err, buf.String())
+	}
+
+	assert.Equal(t, map[string]any{"id": "2", "name": "foo"}, deadLetterRecord.Record)
+	assert.Equal(t, "load", deadLetterRecord.Stage)
+	assert.Contains(t, deadLetterRecord.Error, "failed to deserialize message")
+	assert.NotEmpty(t, deadLetterRecord.Timestamp)
+}
+
+func Test_multiple_outputs(t *testing.T) {
+	newOutput := func(buf *bytes.Buffer) *OutputPluginFile {
+		output, err := NewOutputPluginFileFromConfig([]byte(`
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual
+`))
+		if err != nil {
+			t.Errorf("Could not create plugin: %s", err)
+		}
+
+		writer := bufio.NewWriter(buf)
+		output.newWriter = func() (io.WriteCloser, error) {
+			return NewNopWriteCloser(writer), nil
+		}
+
+		return output
+	}
+
+	buf1 := new(bytes.Buffer)
+	buf2 := new(bytes.Buffer)
+	output1 := newOutput(buf1)
+	output2 := newOutput(buf2)
+	output2.deserialize = func(i GallonRecord) ([]byte, error) {
+		id, _ := i.Get("id")
+		if id == "2" {
+			return nil, errors.New("error")
+		}
+
+		j, err := json.Marshal(&i)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(j, '\n'), nil
+	}
+
+	page := []GallonRecord{}
+	for _, id := range []string{"1", "2", "3"} {
+		r := NewGallonRecord()
+		r.Set("id", id)
+
+		page = append(page, r)
+	}
+
+	g := Gallon{
+		Logger:  logger,
+		Input:   NewInputPluginStub([][]GallonRecord{page}),
+		Output:  output1,
+		Outputs: []OutputPlugin{output2},
+	}
+
+	result, err := g.RunWithResult(context.Background())
+	if err != nil {
+		t.Errorf("Could not run command: %s", err)
+	}
+
+	assert.Equal(t, 3, result.ExtractedRecords)
+	assert.Equal(t, 2, result.LoadedRecords)
+	assert.Equal(t, 1, result.RejectedRecords)
+	assert.Len(t, result.Outputs, 2)
+	assert.Equal(t, 3, result.Outputs[0].LoadedRecords)
+	assert.Equal(t, 0, result.Outputs[0].RejectedRecords)
+	assert.Equal(t, 2, result.Outputs[1].LoadedRecords)
+	assert.Equal(t, 1, result.Outputs[1].RejectedRecords)
+
+	assert.Equal(t, "{\"id\":\"1\"}\n{\"id\":\"2\"}\n{\"id\":\"3\"}\n", buf1.String())
+	assert.Equal(t, "{\"id\":\"1\"}\n{\"id\":\"3\"}\n", buf2.String())
+}
+
+func Test_output_failure_policy(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy OutputFailurePolicy
+	}{
+		{name: "abort", policy: OutputFailureAbort},
+		{name: "continue", policy: OutputFailureContinue},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			failing, err := NewOutputPluginFileFromConfig([]byte(`
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual
+`))
+			if err != nil {
+				t.Errorf("Could not create plugin: %s", err)
+			}
+
+			loadErr := errors.New("load job failed")
+			failing.newWriter = func() (io.WriteCloser, error) {
+				return nil, loadErr
+			}
+
+			output, err := NewOutputPluginFileFromConfig([]byte(`
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual
+`))
+			if err != nil {
+				t.Errorf("Could not create plugin: %s", err)
+			}
+
+			buf := new(bytes.Buffer)
+			writer := bufio.NewWriter(buf)
+			output.newWriter = func() (io.WriteCloser, error) {
+				return NewNopWriteCloser(writer), nil
+			}
+
+			data := [][]GallonRecord{}
+			for i := 0; i < 3; i++ {
+				r := NewGallonRecord()
+				r.Set("id", fmt.Sprintf("%v", i))
+
+				data = append(data, []GallonRecord{r})
+			}
+
+			g := Gallon{
+				Logger:          logger,
+				Input:           NewInputPluginStub(data),
+				Output:          failing,
+				Outputs:         []OutputPlugin{output},
+				OnOutputFailure: tc.policy,
+			}
+
+			result, err := g.RunWithResult(context.Background())
+
+			var gallonErr *GallonError
+			if !errors.As(err, &gallonErr) {
+				t.Fatalf("Expected GallonError, got: %v", err)
+			}
+			assert.Equal(t, GallonStageLoad, gallonErr.Stage)
+			assert.ErrorIs(t, err, loadErr)
+			assert.ErrorIs(t, result.Outputs[0].Err, loadErr)
+
+			if tc.policy == OutputFailureContinue {
+				assert.NoError(t, result.Outputs[1].Err)
+				assert.Equal(t, 3, result.Outputs[1].LoadedRecords)
+				assert.Equal(t, "{\"id\":\"0\"}\n{\"id\":\"1\"}\n{\"id\":\"2\"}\n", buf.String())
+			}
+		})
+	}
+}
+
+// outputPluginFlushOnCancel calls onLoad for each batch, and flushes the loaded records when the context is cancelled.
+type outputPluginFlushOnCancel struct {
+	onLoad  func()
+	loaded  int
+	flushed int
+}
+
+func (p *outputPluginFlushOnCancel) ReplaceLogger(logger logr.Logger) {
+}
+
+func (p *outputPluginFlushOnCancel) Cleanup() error {
+	return nil
+}
+
+func (p *outputPluginFlushOnCancel) Load(ctx context.Context, messages chan []GallonRecord, errs chan error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			p.flushed = p.loaded
+			return nil
+		case msgs, ok := <-messages:
+			if !ok {
+				p.flushed = p.loaded
+				return nil
+			}
+
+			p.loaded += len(msgs)
+			p.onLoad()
+		}
+	}
+}
+
+func Test_cancel(t *testing.T) {
+	r := NewGallonRecord()
+	r.Set("id", "1")
+
+	data := [][]GallonRecord{}
+	for i := 0; i < 100; i++ {
+		data = append(data, []GallonRecord{r})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	output := &outputPluginFlushOnCancel{onLoad: cancel}
+
+	g := Gallon{
+		Logger: logger,
+		Input:  NewInputPluginStub(data),
+		Output: output,
+	}
+
+	result, err := g.RunWithResult(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, result.ExtractedRecords, 100)
+	assert.Greater(t, output.loaded, 0)
+	// the output has finished before Run returns
+	assert.Equal(t, output.loaded, output.flushed)
+}
+
+func Test_rate_limit(t *testing.T) {
+	newPages := func(pages int, size int) [][]GallonRecord {
+		r := NewGallonRecord()
+		r.Set("id", "1")
+
+		data := [][]GallonRecord{}
+		for i := 0; i < pages; i++ {
+			page := []GallonRecord{}
+			for j := 0; j < size; j++ {
+				page = append(page, r)
+			}
+			data = append(data, page)
+		}
+
+		return data
+	}
+
+	tests := []struct {
+		name        string
+		data        [][]GallonRecord
+		limit       RateLimit
+		minDuration time.Duration
+	}{
+		{
+			name:        "batches per second",
+			data:        newPages(5, 1),
+			limit:       RateLimit{BatchesPerSecond: 20},
+			minDuration: 200 * time.Millisecond,
+		},
+		{
+			name:        "records per second",
+			data:        newPages(3, 50),
+			limit:       RateLimit{RecordsPerSecond: 100},
+			minDuration: 500 * time.Millisecond,
+		},
+		{
+			name:        "batch larger than records per second",
+			data:        newPages(1, 150),
+			limit:       RateLimit{RecordsPerSecond: 100},
+			minDuration: 500 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := &outputPluginFlushOnCancel{onLoad: func() {}}
+
+			g := Gallon{
+				Logger:    logger,
+				Input:     NewInputPluginStub(tt.data),
+				Output:    output,
+				RateLimit: tt.limit,
+			}
+
+			startedAt := time.Now()
+			result, err := g.RunWithResult(context.Background())
+			assert.NoError(t, err)
+			assert.GreaterOrEqual(t, time.Since(startedAt), tt.minDuration)
+			assert.Equal(t, len(tt.data)*len(tt.data[0]), result.LoadedRecords)
+		})
+	}
+}
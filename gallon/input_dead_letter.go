@@ -0,0 +1,174 @@
+package gallon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/go-logr/logr"
+)
+
+// InputPluginDeadLetter is an input plugin which extracts the records of dead letters, to replay them.
+// See ReadDeadLetters for reading dead letters from a file.
+//
+// schema is the schema of the records (e.g. the one sent to the outputs in the original migration, See TransformedSchema), or nil if it is unknown.
+// The numbers read by ReadDeadLetters (json.Number) are converted to the types of the fields in the schema: int64 for `int`, float64 for `float`
+// and a string for `number` and `string`. The numbers of the other fields are int64 if they are integers, or float64 otherwise.
+type InputPluginDeadLetter struct {
+	logger      logr.Logger
+	deadLetters []DeadLetter
+	pageSize    int
+	schema      *GallonSchema
+}
+
+func NewInputPluginDeadLetter(
+	deadLetters []DeadLetter,
+	pageSize int,
+	schema *GallonSchema,
+) *InputPluginDeadLetter {
+	return &InputPluginDeadLetter{
+		deadLetters: deadLetters,
+		pageSize:    pageSize,
+		schema:      schema,
+	}
+}
+
+var _ InputPlugin = &InputPluginDeadLetter{}
+var _ SchemaInputPlugin = &InputPluginDeadLetter{}
+
+func (p *InputPluginDeadLetter) ReplaceLogger(logger logr.Logger) {
+	p.logger = logger
+}
+
+func (p *InputPluginDeadLetter) Cleanup() error {
+	return nil
+}
+
+func (p *InputPluginDeadLetter) metricLabels() (string, string) {
+	return "deadLetter", ""
+}
+
+func (p *InputPluginDeadLetter) Schema() *GallonSchema {
+	return p.schema
+}
+
+func (p *InputPluginDeadLetter) Extract(
+	ctx context.Context,
+	messages chan []GallonRecord,
+	errs chan error,
+) error {
+	extractedTotal := 0
+	index := 0
+
+loop:
+	for index < len(p.deadLetters) {
+		select {
+		case <-ctx.Done():
+			break loop
+		default:
+			msgs := []GallonRecord{}
+			for ; index < len(p.deadLetters) && len(msgs) < p.pageSize; index++ {
+				deadLetter := p.deadLetters[index]
+
+				record, ok := deadLetter.Record.(*GallonRecord)
+				if !ok {
+					errs <- NewRecordError(deadLetter.Record, errors.New("record is not an object: "+fmt.Sprintf("%v", deadLetter.Record)))
+					continue
+				}
+
+				msgs = append(msgs, p.convert(record))
+			}
+
+			if len(msgs) > 0 {
+				messages <- msgs
+
+				extractedTotal += len(msgs)
+				p.logger.Info(fmt.Sprintf("extracted %v records", extractedTotal))
+			}
+		}
+	}
+
+	return nil
+}
+
+// convert returns a copy of the record whose numbers are converted to the types of the fields in the schema.
+func (p *InputPluginDeadLetter) convert(record *GallonRecord) GallonRecord {
+	converted := NewGallonRecord()
+	for _, key := range record.Keys() {
+		value, _ := record.Get(key)
+
+		var field *GallonField
+		if p.schema != nil {
+			if f, ok := p.schema.Field(key); ok {
+				field = &f
+			}
+		}
+
+		converted.Set(key, convertDeadLetterValue(field, value))
+	}
+
+	return converted
+}
+
+// convertDeadLetterValue converts the json.Number values in the value to the type of the field, which is nil if it is unknown.
+func convertDeadLetterValue(field *GallonField, value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		t := GallonTypeAny
+		if field != nil {
+			t = field.Type
+		}
+
+		switch t {
+		case GallonTypeNumber, GallonTypeString:
+			return v.String()
+		case GallonTypeFloat, GallonTypeJSON:
+			if f, err := v.Float64(); err == nil {
+				return f
+			}
+		default:
+			if i, err := v.Int64(); err == nil {
+				return i
+			}
+			if f, err := v.Float64(); err == nil {
+				return f
+			}
+		}
+
+		return v.String()
+	case map[string]any:
+		converted := map[string]any{}
+		for key, item := range v {
+			var itemField *GallonField
+			if field != nil && field.Type == GallonTypeJSON {
+				itemField = field
+			} else if field != nil {
+				if i := slices.IndexFunc(field.Fields, func(f GallonField) bool { return f.Name == key }); i >= 0 {
+					itemField = &field.Fields[i]
+				}
+			}
+
+			converted[key] = convertDeadLetterValue(itemField, item)
+		}
+
+		return converted
+	case []any:
+		var itemField *GallonField
+		if field != nil && field.Type == GallonTypeJSON {
+			itemField = field
+		} else if field != nil {
+			itemField = field.Items
+		}
+
+		converted := make([]any, len(v))
+		for i, item := range v {
+			converted[i] = convertDeadLetterValue(itemField, item)
+		}
+
+		return converted
+	default:
+		return value
+	}
+}
@@ -2,8 +2,12 @@ package gallon
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -11,6 +15,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,6 +26,12 @@ type InputPluginDynamoDb struct {
 	tableName string
 	pageSize  int
 	serialize func(map[string]types.AttributeValue) (GallonRecord, error)
+	// startKey and finished are set by Resume
+	startKey      map[string]types.AttributeValue
+	finished      bool
+	cursorHandler func(cursor json.RawMessage)
+	// schema is set by the FromConfig function
+	schema *GallonSchema
 }
 
 func NewInputPluginDynamoDb(
@@ -37,6 +49,91 @@ func NewInputPluginDynamoDb(
 }
 
 var _ InputPlugin = &InputPluginDynamoDb{}
+var _ ResumableInputPlugin = &InputPluginDynamoDb{}
+var _ CountingInputPlugin = &InputPluginDynamoDb{}
+var _ SchemaInputPlugin = &InputPluginDynamoDb{}
+
+// inputPluginDynamoDbCursor is the cursor of InputPluginDynamoDb for checkpoints.
+// LastEvaluatedKey is null when the scan has finished.
+type inputPluginDynamoDbCursor struct {
+	LastEvaluatedKey map[string]dynamoDbKeyAttribute `json:"lastEvaluatedKey"`
+}
+
+// dynamoDbKeyAttribute is a JSON representation of a key attribute, which is either string, number or binary.
+type dynamoDbKeyAttribute struct {
+	S *string `json:"S,omitempty"`
+	N *string `json:"N,omitempty"`
+	B []byte  `json:"B,omitempty"`
+}
+
+func encodeDynamoDbKey(key map[string]types.AttributeValue) (map[string]dynamoDbKeyAttribute, error) {
+	if key == nil {
+		return nil, nil
+	}
+
+	result := map[string]dynamoDbKeyAttribute{}
+	for k, v := range key {
+		switch v := v.(type) {
+		case *types.AttributeValueMemberS:
+			result[k] = dynamoDbKeyAttribute{S: aws.String(v.Value)}
+		case *types.AttributeValueMemberN:
+			result[k] = dynamoDbKeyAttribute{N: aws.String(v.Value)}
+		case *types.AttributeValueMemberB:
+			result[k] = dynamoDbKeyAttribute{B: v.Value}
+		default:
+			return nil, fmt.Errorf("unsupported key type: %v for key: %v", v, k)
+		}
+	}
+
+	return result, nil
+}
+
+func decodeDynamoDbKey(key map[string]dynamoDbKeyAttribute) (map[string]types.AttributeValue, error) {
+	if key == nil {
+		return nil, nil
+	}
+
+	result := map[string]types.AttributeValue{}
+	for k, v := range key {
+		switch {
+		case v.S != nil:
+			result[k] = &types.AttributeValueMemberS{Value: *v.S}
+		case v.N != nil:
+			result[k] = &types.AttributeValueMemberN{Value: *v.N}
+		case v.B != nil:
+			result[k] = &types.AttributeValueMemberB{Value: v.B}
+		default:
+			return nil, fmt.Errorf("invalid key attribute: %v", k)
+		}
+	}
+
+	return result, nil
+}
+
+func (p *InputPluginDynamoDb) Resume(cursor json.RawMessage) error {
+	var c inputPluginDynamoDbCursor
+	if err := json.Unmarshal(cursor, &c); err != nil {
+		return fmt.Errorf("invalid cursor for dynamodb: %v (error: %v)", string(cursor), err)
+	}
+
+	key, err := decodeDynamoDbKey(c.LastEvaluatedKey)
+	if err != nil {
+		return fmt.Errorf("invalid cursor for dynamodb: %v (error: %v)", string(cursor), err)
+	}
+
+	p.startKey = key
+	p.finished = key == nil
+
+	return nil
+}
+
+func (p *InputPluginDynamoDb) SetCursorHandler(handler func(cursor json.RawMessage)) {
+	p.cursorHandler = handler
+}
+
+func (p *InputPluginDynamoDb) Schema() *GallonSchema {
+	return p.schema
+}
 
 func (p *InputPluginDynamoDb) ReplaceLogger(logger logr.Logger) {
 	if p.tableName != "" {
@@ -51,13 +148,22 @@ func (p *InputPluginDynamoDb) Cleanup() error {
 	return nil
 }
 
+func (p *InputPluginDynamoDb) metricLabels() (string, string) {
+	return "dynamodb", p.tableName
+}
+
 func (p *InputPluginDynamoDb) Extract(
 	ctx context.Context,
 	messages chan []GallonRecord,
 	errs chan error,
 ) error {
-	hasNext := true
-	lastEvaluatedKey := map[string]types.AttributeValue(nil)
+	hasNext := !p.finished
+	lastEvaluatedKey := p.startKey
+	if p.finished {
+		p.logger.Info("the scan has already finished")
+	} else if lastEvaluatedKey != nil {
+		p.logger.Info("resume from the last evaluated key")
+	}
 
 	extractedTotal := 0
 
@@ -67,17 +173,31 @@ loop:
 		case <-ctx.Done():
 			break loop
 		default:
+			pageStartedAt := time.Now()
+			scanCtx, span := tracer.Start(ctx, "dynamodb.Scan", trace.WithAttributes(
+				attribute.String("db.system", "dynamodb"),
+				attribute.String("gallon.table", p.tableName),
+				attribute.Int("gallon.page_size", p.pageSize),
+			))
 			resp, err := p.client.Scan(
-				context.TODO(),
+				scanCtx,
 				&dynamodb.ScanInput{
 					TableName:         aws.String(p.tableName),
 					ExclusiveStartKey: lastEvaluatedKey,
 					Limit:             aws.Int32(int32(p.pageSize)),
 				},
 			)
+			if err == nil {
+				span.SetAttributes(attribute.Int("gallon.records", len(resp.Items)))
+			}
+			endSpan(span, err)
+			if err != nil && ctx.Err() != nil {
+				// the scan is interrupted by the cancellation
+				break loop
+			}
 			if err != nil {
-				errs <- fmt.Errorf("failed to scan dynamodb table: %v (error: %v)", p.tableName, err)
-				break
+				// the same page would be scanned again, so the error is fatal
+				return fmt.Errorf("failed to scan dynamodb table: %v (error: %w)", p.tableName, err)
 			}
 
 			if resp.LastEvaluatedKey != nil {
@@ -91,7 +211,10 @@ loop:
 			for _, item := range resp.Items {
 				record, err := p.serialize(item)
 				if err != nil {
-					errs <- fmt.Errorf("failed to serialize dynamodb record: %v (error: %w)", item, err)
+					errs <- NewRecordError(
+						rawDynamoDbRecord(item),
+						fmt.Errorf("failed to serialize dynamodb record: %v (error: %w)", item, err),
+					)
 					continue
 				}
 
@@ -99,6 +222,21 @@ loop:
 			}
 
 			if len(msgs) > 0 {
+				if p.cursorHandler != nil {
+					key, err := encodeDynamoDbKey(resp.LastEvaluatedKey)
+					if err != nil {
+						return err
+					}
+
+					cursor, err := json.Marshal(inputPluginDynamoDbCursor{LastEvaluatedKey: key})
+					if err != nil {
+						return err
+					}
+
+					p.cursorHandler(cursor)
+				}
+
+				observeBatchDuration(GallonStageExtract, p, pageStartedAt)
 				messages <- msgs
 
 				extractedTotal += len(msgs)
@@ -110,6 +248,46 @@ loop:
 	return nil
 }
 
+// Count scans the whole table with `Select: COUNT`, which returns the number of items without the items.
+func (p *InputPluginDynamoDb) Count(ctx context.Context) (int, error) {
+	count := 0
+
+	var lastEvaluatedKey map[string]types.AttributeValue
+	for {
+		resp, err := p.client.Scan(
+			ctx,
+			&dynamodb.ScanInput{
+				TableName:         aws.String(p.tableName),
+				ExclusiveStartKey: lastEvaluatedKey,
+				Select:            types.SelectCount,
+			},
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count dynamodb table: %v (error: %v)", p.tableName, err)
+		}
+
+		count += int(resp.Count)
+
+		if resp.LastEvaluatedKey == nil {
+			break
+		}
+		lastEvaluatedKey = resp.LastEvaluatedKey
+	}
+
+	return count, nil
+}
+
+// rawDynamoDbRecord converts an item into a JSON serializable value for dead letters.
+func rawDynamoDbRecord(item map[string]types.AttributeValue) any {
+	anySchema := InputPluginDynamoDbConfigSchemaColumn{Type: "any"}
+	value, err := anySchema.getValue(&types.AttributeValueMemberM{Value: item})
+	if err != nil {
+		return fmt.Sprintf("%v", item)
+	}
+
+	return value
+}
+
 type InputPluginDynamoDbConfig struct {
 	Table    string                                           `yaml:"table"`
 	Schema   map[string]InputPluginDynamoDbConfigSchemaColumn `yaml:"schema"`
@@ -122,9 +300,12 @@ type InputPluginDynamoDbConfigSchemaColumn struct {
 	Type       string                                           `yaml:"type"`
 	Properties map[string]InputPluginDynamoDbConfigSchemaColumn `yaml:"properties,omitempty"`
 	Items      *InputPluginDynamoDbConfigSchemaColumn           `yaml:"items,omitempty"`
-	Rename     *string                                          `yaml:"rename"`
+	Rename     *string                                          `yaml:"rename,omitempty"`
 }
 
+// dynamoDbColumnTypes are the types of the schema columns handled by getValue.
+var dynamoDbColumnTypes = []string{"string", "number", "boolean", "object", "array", "any"}
+
 func (c InputPluginDynamoDbConfigSchemaColumn) getValue(v types.AttributeValue) (any, error) {
 	switch c.Type {
 	case "string":
@@ -235,27 +416,63 @@ func (c InputPluginDynamoDbConfigSchemaColumn) getValue(v types.AttributeValue)
 	}
 }
 
-func NewInputPluginDynamoDbFromConfig(configYml []byte) (*InputPluginDynamoDb, error) {
-	var inConfig GallonConfig[InputPluginDynamoDbConfig, any]
-	if err := yaml.Unmarshal(configYml, &inConfig); err != nil {
-		return nil, err
+// gallonSchema returns the schema of the records converted by the schema, after the rename.
+func (c InputPluginDynamoDbConfig) gallonSchema() *GallonSchema {
+	return &GallonSchema{Fields: dynamoDbGallonFields(c.Schema, true)}
+}
+
+func dynamoDbGallonFields(schema map[string]InputPluginDynamoDbConfigSchemaColumn, rename bool) []GallonField {
+	keys := slices.Sorted(maps.Keys(schema))
+
+	fields := []GallonField{}
+	for _, key := range keys {
+		column := schema[key]
+
+		columnName := key
+		// properties of an object are not renamed
+		if rename && column.Rename != nil {
+			columnName = *column.Rename
+		}
+
+		fields = append(fields, column.gallonField(columnName))
 	}
 
-	dbConfig := inConfig.In
-	if dbConfig.PageSize == 0 {
-		dbConfig.PageSize = 1000
+	return fields
+}
+
+func (c InputPluginDynamoDbConfigSchemaColumn) gallonField(name string) GallonField {
+	t := c.Type
+	switch t {
+	case "boolean":
+		t = "bool"
+	}
+
+	field := GallonField{
+		Name:     name,
+		Type:     GallonType(t),
+		Nullable: true,
+		Fields:   dynamoDbGallonFields(c.Properties, false),
+		source:   "dynamodb " + c.Type,
 	}
+	if c.Items != nil {
+		items := c.Items.gallonField("")
+		field.Items = &items
+	}
+
+	return field
+}
 
+func (c InputPluginDynamoDbConfig) newClient() (*dynamodb.Client, error) {
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
 		return nil, err
 	}
-	cfg.Region = dbConfig.Region
+	cfg.Region = c.Region
 
-	if dbConfig.Endpoint != nil {
+	if c.Endpoint != nil {
 		cfg.EndpointResolverWithOptions = aws.EndpointResolverWithOptionsFunc(
 			func(service, region string, options ...any) (aws.Endpoint, error) {
-				return aws.Endpoint{URL: *dbConfig.Endpoint}, nil
+				return aws.Endpoint{URL: *c.Endpoint}, nil
 			})
 		cfg.Credentials = credentials.StaticCredentialsProvider{
 			Value: aws.Credentials{
@@ -265,13 +482,30 @@ func NewInputPluginDynamoDbFromConfig(configYml []byte) (*InputPluginDynamoDb, e
 		}
 	}
 
-	client := dynamodb.NewFromConfig(cfg)
+	return dynamodb.NewFromConfig(cfg), nil
+}
+
+func NewInputPluginDynamoDbFromConfig(configYml []byte) (*InputPluginDynamoDb, error) {
+	var inConfig GallonConfig[InputPluginDynamoDbConfig, any]
+	if err := yaml.Unmarshal(configYml, &inConfig); err != nil {
+		return nil, err
+	}
+
+	dbConfig := inConfig.In
+	if dbConfig.PageSize == 0 {
+		dbConfig.PageSize = 1000
+	}
+
+	client, err := dbConfig.newClient()
+	if err != nil {
+		return nil, err
+	}
 
 	if dbConfig.Table == "" {
 		return nil, fmt.Errorf("table_name is required")
 	}
 
-	return NewInputPluginDynamoDb(
+	plugin := NewInputPluginDynamoDb(
 		client,
 		dbConfig.Table,
 		dbConfig.PageSize,
@@ -299,5 +533,8 @@ func NewInputPluginDynamoDbFromConfig(configYml []byte) (*InputPluginDynamoDb, e
 
 			return record, nil
 		},
-	), nil
+	)
+	plugin.schema = dbConfig.gallonSchema()
+
+	return plugin, nil
 }
@@ -0,0 +1,179 @@
+package gallon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v3"
+)
+
+// InputPluginExec extracts the records from a command over JSON lines. See execMessage for the protocol.
+type InputPluginExec struct {
+	logger logr.Logger
+	config ExecConfig
+}
+
+func NewInputPluginExec(
+	config ExecConfig,
+) *InputPluginExec {
+	return &InputPluginExec{
+		config: config,
+	}
+}
+
+var _ InputPlugin = &InputPluginExec{}
+
+func (p *InputPluginExec) ReplaceLogger(logger logr.Logger) {
+	p.logger = logger
+}
+
+func (p *InputPluginExec) Cleanup() error {
+	return nil
+}
+
+func (p *InputPluginExec) metricLabels() (string, string) {
+	return "exec", p.config.Command
+}
+
+func (p *InputPluginExec) Extract(
+	ctx context.Context,
+	messages chan []GallonRecord,
+	errs chan error,
+) error {
+	cmd := newExecCommand(ctx, p.logger, p.config)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start command: %v (error: %v)", p.config.Command, err)
+	}
+
+	// the command must exit even if the records are not read to the end
+	stopped := false
+	defer func() {
+		if !stopped {
+			_ = cmd.Cancel()
+			_ = cmd.Wait()
+		}
+	}()
+
+	// the command may exit without reading stdin, so the errors are ignored here and the exit status is checked later
+	_ = json.NewEncoder(stdin).Encode(execMessage{Type: "init", Config: p.config.Config})
+	_ = stdin.Close()
+
+	var columns []ExecColumn
+	extractedTotal := 0
+
+	decoder := json.NewDecoder(stdout)
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		default:
+			var msg execMessage
+			if err := decoder.Decode(&msg); err != nil {
+				if errors.Is(err, io.EOF) {
+					break loop
+				}
+
+				if ctx.Err() != nil {
+					break loop
+				}
+
+				return fmt.Errorf("invalid message from command: %v", err)
+			}
+
+			switch msg.Type {
+			case "schema":
+				columns = msg.Columns
+			case "error":
+				errs <- newExecError(msg)
+			case "records":
+				msgs := []GallonRecord{}
+				for _, record := range msg.Records {
+					if record == nil {
+						errs <- errors.New("record is null")
+						continue
+					}
+
+					converted, err := p.convert(*record, columns)
+					if err != nil {
+						errs <- NewRecordError(record, err)
+						continue
+					}
+
+					msgs = append(msgs, converted)
+				}
+
+				if len(msgs) > 0 {
+					messages <- msgs
+
+					extractedTotal += len(msgs)
+					p.logger.Info(fmt.Sprintf("extracted %v records", extractedTotal))
+				}
+			default:
+				return fmt.Errorf("unknown message type from command: %v", msg.Type)
+			}
+		}
+	}
+
+	stopped = true
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		return fmt.Errorf("command failed: %v (error: %v)", p.config.Command, err)
+	}
+
+	return nil
+}
+
+// convert converts the values by the schema. The record is returned as is if there is no schema.
+func (p *InputPluginExec) convert(record GallonRecord, columns []ExecColumn) (GallonRecord, error) {
+	if columns == nil {
+		return record, nil
+	}
+
+	result := NewGallonRecord()
+	for _, column := range columns {
+		value, ok := record.Get(column.Name)
+		if !ok {
+			continue
+		}
+
+		v, err := column.getValue(value)
+		if err != nil {
+			return GallonRecord{}, fmt.Errorf("failed to get value for column: %v (error: %v)", column.Name, err)
+		}
+
+		result.Set(column.Name, v)
+	}
+
+	return result, nil
+}
+
+func NewInputPluginExecFromConfig(configYml []byte) (*InputPluginExec, error) {
+	var inConfig GallonConfig[ExecConfig, any]
+	if err := yaml.Unmarshal(configYml, &inConfig); err != nil {
+		return nil, err
+	}
+
+	if inConfig.In.Command == "" {
+		return nil, errors.New("command is required for exec plugin")
+	}
+
+	return NewInputPluginExec(inConfig.In), nil
+}
@@ -0,0 +1,90 @@
+package gallon
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+)
+
+// InputPluginLimit extracts at most limit records from the input plugin, and stops it after that.
+type InputPluginLimit struct {
+	input InputPlugin
+	limit int
+}
+
+func NewInputPluginLimit(input InputPlugin, limit int) *InputPluginLimit {
+	return &InputPluginLimit{
+		input: input,
+		limit: limit,
+	}
+}
+
+var _ InputPlugin = &InputPluginLimit{}
+var _ SchemaInputPlugin = &InputPluginLimit{}
+
+func (p *InputPluginLimit) Schema() *GallonSchema {
+	if input, ok := p.input.(SchemaInputPlugin); ok {
+		return input.Schema()
+	}
+
+	return nil
+}
+
+func (p *InputPluginLimit) ReplaceLogger(logger logr.Logger) {
+	p.input.ReplaceLogger(logger)
+}
+
+func (p *InputPluginLimit) Cleanup() error {
+	return p.input.Cleanup()
+}
+
+func (p *InputPluginLimit) metricLabels() (string, string) {
+	return metricLabelsOf(p.input)
+}
+
+func (p *InputPluginLimit) Extract(
+	ctx context.Context,
+	messages chan []GallonRecord,
+	errs chan error,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	extracted := make(chan []GallonRecord)
+	extractErr := make(chan error, 1)
+	go func() {
+		defer close(extracted)
+
+		extractErr <- p.input.Extract(ctx, extracted, errs)
+	}()
+
+	remaining := p.limit
+	for msgs := range extracted {
+		if remaining <= 0 || ctx.Err() != nil {
+			// the input plugin may send more batches until it notices the cancellation
+			continue
+		}
+
+		if len(msgs) > remaining {
+			msgs = msgs[:remaining]
+		}
+
+		select {
+		case <-ctx.Done():
+		case messages <- msgs:
+			remaining -= len(msgs)
+		}
+
+		if remaining <= 0 {
+			cancel()
+		}
+	}
+
+	err := <-extractErr
+	if remaining <= 0 {
+		// the error caused by stopping the input plugin is not a failure
+		return nil
+	}
+
+	return err
+}
@@ -43,12 +43,20 @@ func (p *InputPluginRandom) Cleanup() error {
 	return nil
 }
 
+func (p *InputPluginRandom) metricLabels() (string, string) {
+	return "random", ""
+}
+
 func (p *InputPluginRandom) Extract(
 	ctx context.Context,
 	messages chan []GallonRecord,
 	errs chan error,
 ) error {
 	for i := 0; i < p.pageLimit; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+
 		records := []GallonRecord{}
 
 		for j := 0; j < p.pageSize; j++ {
@@ -83,6 +91,9 @@ type InputPluginRandomConfigSchemaColumn struct {
 	Fields orderedmap.OrderedMap[string, InputPluginRandomConfigSchemaColumn] `yaml:"fields"`
 }
 
+// randomColumnTypes are the types of the schema columns handled by generateValue.
+var randomColumnTypes = []string{"string", "int", "float", "bool", "name", "url", "email", "uuid", "time", "unixtime", "record"}
+
 func (c InputPluginRandomConfigSchemaColumn) generateValue(index int) (any, error) {
 	switch c.Type {
 	case "string":
@@ -13,6 +13,8 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v3"
 )
 
@@ -62,6 +64,11 @@ type InputPluginSql struct {
 	driver    string
 	pageSize  int
 	serialize func(orderedmap.OrderedMap[string, any]) (GallonRecord, error)
+	// startPage is the page to start from, set by Resume
+	startPage     int
+	cursorHandler func(cursor json.RawMessage)
+	// schema is set by the FromConfig function
+	schema *GallonSchema
 }
 
 func NewInputPluginSql(
@@ -83,6 +90,34 @@ func NewInputPluginSql(
 }
 
 var _ InputPlugin = &InputPluginSql{}
+var _ ResumableInputPlugin = &InputPluginSql{}
+var _ CountingInputPlugin = &InputPluginSql{}
+var _ SchemaInputPlugin = &InputPluginSql{}
+
+// inputPluginSqlCursor is the cursor of InputPluginSql for checkpoints.
+// Since pages are fetched by LIMIT/OFFSET, the order of rows must be stable for resuming.
+type inputPluginSqlCursor struct {
+	Page int `json:"page"`
+}
+
+func (p *InputPluginSql) Resume(cursor json.RawMessage) error {
+	var c inputPluginSqlCursor
+	if err := json.Unmarshal(cursor, &c); err != nil {
+		return fmt.Errorf("invalid cursor for sql: %v (error: %v)", string(cursor), err)
+	}
+
+	p.startPage = c.Page
+
+	return nil
+}
+
+func (p *InputPluginSql) SetCursorHandler(handler func(cursor json.RawMessage)) {
+	p.cursorHandler = handler
+}
+
+func (p *InputPluginSql) Schema() *GallonSchema {
+	return p.schema
+}
 
 func (p *InputPluginSql) ReplaceLogger(logger logr.Logger) {
 	if p.tableName != "" {
@@ -102,6 +137,10 @@ func (p *InputPluginSql) Cleanup() error {
 	return p.client.Close()
 }
 
+func (p *InputPluginSql) metricLabels() (string, string) {
+	return "sql", p.sourceName()
+}
+
 func (p *InputPluginSql) sourceName() string {
 	if p.rawQuery != "" {
 		return "raw_query"
@@ -115,7 +154,10 @@ func (p *InputPluginSql) Extract(
 	errs chan error,
 ) error {
 	hasNext := true
-	page := 0
+	page := p.startPage
+	if page > 0 {
+		p.logger.Info(fmt.Sprintf("resume from page %v", page))
+	}
 
 	extractedTotal := 0
 
@@ -156,7 +198,7 @@ func (p *InputPluginSql) Extract(
 		}
 	}
 
-	query, err := p.client.Prepare(pagedQueryStatement)
+	query, err := p.client.PrepareContext(ctx, pagedQueryStatement)
 	if err != nil {
 		return err
 	}
@@ -172,48 +214,27 @@ loop:
 		case <-ctx.Done():
 			break loop
 		default:
-			rows, err := query.Query(page * p.pageSize)
-			if err != nil {
-				return err
-			}
-			if err := rows.Err(); err != nil {
-				return err
+			pageStartedAt := time.Now()
+			msgs, err := p.queryPage(ctx, query, page, errs)
+			if err != nil && ctx.Err() != nil {
+				// the query is interrupted by the cancellation
+				break loop
 			}
-
-			cols, err := rows.Columns()
 			if err != nil {
 				return err
 			}
 
-			msgs := []GallonRecord{}
-			for rows.Next() {
-				columns := make([]any, len(cols))
-				columnPointers := make([]any, len(cols))
-				for i := range columns {
-					columnPointers[i] = &columns[i]
-				}
-
-				if err := rows.Scan(columnPointers...); err != nil {
-					errs <- fmt.Errorf("failed to scan sql table: %v (error: %v)", p.sourceName(), err)
-					continue
-				}
-
-				record := *orderedmap.New[string, any]()
-				for i, colName := range cols {
-					val := columnPointers[i].(*any)
-					record.Set(colName, *val)
-				}
+			if len(msgs) > 0 {
+				if p.cursorHandler != nil {
+					cursor, err := json.Marshal(inputPluginSqlCursor{Page: page + 1})
+					if err != nil {
+						return err
+					}
 
-				r, err := p.serialize(record)
-				if err != nil {
-					errs <- fmt.Errorf("failed to serialize sql table: %v (error: %v)", p.sourceName(), err)
-					continue
+					p.cursorHandler(cursor)
 				}
 
-				msgs = append(msgs, r)
-			}
-
-			if len(msgs) > 0 {
+				observeBatchDuration(GallonStageExtract, p, pageStartedAt)
 				messages <- msgs
 				extractedTotal += len(msgs)
 
@@ -232,6 +253,99 @@ loop:
 	return nil
 }
 
+// Count runs COUNT(*) on the table or the raw query.
+func (p *InputPluginSql) Count(ctx context.Context) (int, error) {
+	statement := fmt.Sprintf("SELECT COUNT(*) FROM %v", p.tableName)
+	if p.rawQuery != "" {
+		statement = fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS __gallon_raw_query", p.rawQuery)
+	}
+
+	var count int
+	if err := p.client.QueryRowContext(ctx, statement).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count sql table: %v (error: %v)", p.sourceName(), err)
+	}
+
+	return count, nil
+}
+
+// queryPage fetches the page and serializes the rows. Rows which fail to be scanned or serialized are sent to errs.
+func (p *InputPluginSql) queryPage(ctx context.Context, query *sql.Stmt, page int, errs chan error) (msgs []GallonRecord, err error) {
+	ctx, span := tracer.Start(ctx, "sql.query", trace.WithAttributes(
+		attribute.String("db.system", p.driver),
+		attribute.String("gallon.table", p.sourceName()),
+		attribute.Int("gallon.page", page),
+		attribute.Int("gallon.offset", page*p.pageSize),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int("gallon.records", len(msgs)))
+		endSpan(span, err)
+	}()
+
+	rows, err := query.QueryContext(ctx, page*p.pageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	msgs = []GallonRecord{}
+	for rows.Next() {
+		columns := make([]any, len(cols))
+		columnPointers := make([]any, len(cols))
+		for i := range columns {
+			columnPointers[i] = &columns[i]
+		}
+
+		if err := rows.Scan(columnPointers...); err != nil {
+			errs <- fmt.Errorf("failed to scan sql table: %v (error: %v)", p.sourceName(), err)
+			continue
+		}
+
+		record := *orderedmap.New[string, any]()
+		for i, colName := range cols {
+			val := columnPointers[i].(*any)
+			record.Set(colName, *val)
+		}
+
+		r, err := p.serialize(record)
+		if err != nil {
+			errs <- NewRecordError(
+				rawSqlRecord(record),
+				fmt.Errorf("failed to serialize sql table: %v (error: %v)", p.sourceName(), err),
+			)
+			continue
+		}
+
+		msgs = append(msgs, r)
+	}
+
+	return msgs, nil
+}
+
+// rawSqlRecord converts a scanned row into a GallonRecord for dead letters.
+// Since drivers return []byte for many column types, they are converted into string to be readable in JSON.
+func rawSqlRecord(item orderedmap.OrderedMap[string, any]) *GallonRecord {
+	record := NewGallonRecord()
+	for pair := item.Oldest(); pair != nil; pair = pair.Next() {
+		if b, ok := pair.Value.([]byte); ok {
+			record.Set(pair.Key, string(b))
+			continue
+		}
+
+		record.Set(pair.Key, pair.Value)
+	}
+
+	return &record
+}
+
 func (p *InputPluginSql) CloseConnection() error {
 	return p.client.Close()
 }
@@ -247,9 +361,9 @@ type InputPluginSqlConfig struct {
 
 type InputPluginSqlConfigSchemaColumn struct {
 	Type            string                                      `yaml:"type"`
-	DefaultTimezone *string                                     `yaml:"default_timezone"`
-	Transforms      []InputPluginSqlConfigSchemaColumnTransform `yaml:"transforms"`
-	Rename          *string                                     `yaml:"rename"`
+	DefaultTimezone *string                                     `yaml:"default_timezone,omitempty"`
+	Transforms      []InputPluginSqlConfigSchemaColumnTransform `yaml:"transforms,omitempty"`
+	Rename          *string                                     `yaml:"rename,omitempty"`
 }
 
 type InputPluginSqlConfigSchemaColumnTransform struct {
@@ -306,6 +420,9 @@ func (c InputPluginSqlConfigSchemaColumnTransform) Transform(sourceType string,
 	return nil, fmt.Errorf("unsupported transform: %v -> %v", sourceType, c.Type)
 }
 
+// sqlColumnTypes are the types of the schema columns handled by getValue.
+var sqlColumnTypes = []string{"string", "int", "float", "decimal", "bool", "date", "time", "json"}
+
 func (c InputPluginSqlConfigSchemaColumn) getValue(value any) (any, error) {
 	// if value is nil, returns nil anyway
 	if value == nil {
@@ -327,19 +444,37 @@ func (c InputPluginSqlConfigSchemaColumn) getValue(value any) (any, error) {
 
 		return nil, fmt.Errorf("value is not string: %v", value)
 	case "int":
-		v, ok := value.(int64)
-		if !ok {
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case []byte:
+			// mysql returns BIT(n) as big-endian bytes
+			if len(v) == 0 || len(v) > 8 {
+				return nil, fmt.Errorf("value is not int: %v", value)
+			}
+
+			var i int64
+			for _, b := range v {
+				i = i<<8 | int64(b)
+			}
+			return i, nil
+		default:
 			return nil, fmt.Errorf("value is not int: %v", value)
 		}
-
-		return v, nil
 	case "float":
-		v, ok := value.(float64)
-		if !ok {
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case float32:
+			// mysql returns FLOAT as float32, which is converted through the decimal representation not to add the noise of float32
+			f, err := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse float: %v", err)
+			}
+			return f, nil
+		default:
 			return nil, fmt.Errorf("value is not float: %v", value)
 		}
-
-		return v, nil
 	case "decimal":
 		// MySQLのdecimal型は[]byteとして返されることがあるため、文字列に変換してからfloat64に変換
 		switch v := value.(type) {
@@ -438,6 +573,40 @@ func (c InputPluginSqlConfigSchemaColumn) getValue(value any) (any, error) {
 	}
 }
 
+// gallonSchema returns the schema of the records converted by the schema, after the transforms and the rename.
+// It returns nil in the raw query mode, since the schema is ignored.
+func (c InputPluginSqlConfig) gallonSchema() *GallonSchema {
+	if c.Query != "" {
+		return nil
+	}
+
+	fields := []GallonField{}
+	for pair := c.Schema.Oldest(); pair != nil; pair = pair.Next() {
+		t := pair.Value.Type
+		switch t {
+		case "decimal":
+			t = "float"
+		}
+		source := "sql " + pair.Value.Type
+
+		for _, transform := range pair.Value.Transforms {
+			if transform.Type != "" && transform.Type != t {
+				t = transform.Type
+				source = fmt.Sprintf("%v (transformed to %v)", source, transform.Type)
+			}
+		}
+
+		columnName := pair.Key
+		if pair.Value.Rename != nil {
+			columnName = *pair.Value.Rename
+		}
+
+		fields = append(fields, GallonField{Name: columnName, Type: GallonType(t), Nullable: true, source: source})
+	}
+
+	return &GallonSchema{Fields: fields}
+}
+
 func NewInputPluginSqlFromConfig(configYml []byte) (*InputPluginSql, error) {
 	var inConfig GallonConfig[InputPluginSqlConfig, any]
 	if err := yaml.Unmarshal(configYml, &inConfig); err != nil {
@@ -476,7 +645,7 @@ func NewInputPluginSqlFromConfig(configYml []byte) (*InputPluginSql, error) {
 	}
 
 	// Table mode: apply schema transformations
-	return NewInputPluginSql(
+	plugin := NewInputPluginSql(
 		db,
 		dbConfig.Table,
 		"",
@@ -517,5 +686,8 @@ func NewInputPluginSqlFromConfig(configYml []byte) (*InputPluginSql, error) {
 
 			return record, nil
 		},
-	), nil
+	)
+	plugin.schema = dbConfig.gallonSchema()
+
+	return plugin, nil
 }
@@ -2,6 +2,7 @@ package gallon
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/go-logr/logr"
@@ -9,6 +10,11 @@ import (
 
 type InputPluginStub struct {
 	data [][]GallonRecord
+	// extractErr is returned from Extract after all data is sent
+	extractErr error
+	// startPage is set by Resume
+	startPage     int
+	cursorHandler func(cursor json.RawMessage)
 }
 
 func NewInputPluginStub(
@@ -20,6 +26,15 @@ func NewInputPluginStub(
 }
 
 var _ InputPlugin = &InputPluginStub{}
+var _ ResumableInputPlugin = &InputPluginStub{}
+
+func (i *InputPluginStub) Resume(cursor json.RawMessage) error {
+	return json.Unmarshal(cursor, &i.startPage)
+}
+
+func (i *InputPluginStub) SetCursorHandler(handler func(cursor json.RawMessage)) {
+	i.cursorHandler = handler
+}
 
 func (i InputPluginStub) ReplaceLogger(logger logr.Logger) {
 }
@@ -33,7 +48,7 @@ func (i InputPluginStub) Extract(
 	messages chan []GallonRecord,
 	errs chan error,
 ) error {
-	p := 0
+	p := i.startPage
 
 	for p < len(i.data) {
 		select {
@@ -41,6 +56,10 @@ func (i InputPluginStub) Extract(
 			return nil
 		default:
 			if len(i.data[p]) > 0 {
+				if i.cursorHandler != nil {
+					i.cursorHandler(json.RawMessage(fmt.Sprintf("%v", p+1)))
+				}
+
 				messages <- i.data[p]
 				logger.Info(fmt.Sprintf("extracted %v records", len(i.data[p])), "page", p)
 			}
@@ -48,5 +67,5 @@ func (i InputPluginStub) Extract(
 		}
 	}
 
-	return nil
+	return i.extractErr
 }
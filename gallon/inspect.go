@@ -0,0 +1,276 @@
+package gallon
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// InspectSqlSchema connects to the database of the config, and returns the schema of the sql input plugin
+// for the columns of the table (by rows.ColumnTypes), in the order of the columns.
+func InspectSqlSchema(ctx context.Context, config InputPluginSqlConfig) (*orderedmap.OrderedMap[string, InputPluginSqlConfigSchemaColumn], error) {
+	if config.Table == "" {
+		return nil, fmt.Errorf("table is required")
+	}
+
+	db, err := sql.Open(config.Driver, config.DatabaseUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %v LIMIT 0", config.Table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query table: %v (error: %v)", config.Table, err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	// the mysql driver does not report the length of BIT columns, which is needed to tell BIT(1) from the others
+	var bitLengths map[string]int64
+	if config.Driver == "mysql" && slices.ContainsFunc(columnTypes, func(c *sql.ColumnType) bool { return c.DatabaseTypeName() == "BIT" }) {
+		bitLengths, err = mysqlBitLengths(ctx, db, config.Table)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	schema := orderedmap.New[string, InputPluginSqlConfigSchemaColumn]()
+	for _, c := range columnTypes {
+		databaseType := c.DatabaseTypeName()
+		if length, ok := bitLengths[c.Name()]; ok {
+			databaseType = fmt.Sprintf("BIT(%v)", length)
+		}
+
+		schema.Set(c.Name(), InputPluginSqlConfigSchemaColumn{Type: sqlColumnType(databaseType)})
+	}
+
+	return schema, nil
+}
+
+// mysqlBitLengths returns the lengths of the BIT columns of the table (`table` or `database.table`) from information_schema.
+func mysqlBitLengths(ctx context.Context, db *sql.DB, table string) (map[string]int64, error) {
+	database, name, ok := strings.Cut(strings.ReplaceAll(table, "`", ""), ".")
+	if !ok {
+		database, name = "", database
+	}
+
+	rows, err := db.QueryContext(
+		ctx,
+		"SELECT COLUMN_NAME, NUMERIC_PRECISION FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ? AND DATA_TYPE = 'bit'",
+		database,
+		name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the lengths of BIT columns: %v (error: %v)", table, err)
+	}
+	defer rows.Close()
+
+	lengths := map[string]int64{}
+	for rows.Next() {
+		var column string
+		var length int64
+		if err := rows.Scan(&column, &length); err != nil {
+			return nil, err
+		}
+
+		lengths[column] = length
+	}
+
+	return lengths, rows.Err()
+}
+
+// sqlColumnType maps the database type name of a column (mysql or postgres) to the type of the schema.
+// BIT is `bool` only for BIT(1) (the default length), and `int` for the longer ones. Unknown types are extracted as strings.
+func sqlColumnType(databaseType string) string {
+	t := strings.ToUpper(databaseType)
+	t = strings.TrimPrefix(t, "UNSIGNED ")
+
+	var length string
+	if i := strings.IndexAny(t, "( "); i >= 0 && t != "DOUBLE PRECISION" {
+		length = strings.Trim(t[i:], "( )")
+		t = t[:i]
+	}
+
+	switch t {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT", "YEAR", "INT2", "INT4", "INT8", "SERIAL", "BIGSERIAL":
+		return "int"
+	case "FLOAT", "DOUBLE", "DOUBLE PRECISION", "REAL", "FLOAT4", "FLOAT8":
+		return "float"
+	case "DECIMAL", "NUMERIC":
+		return "decimal"
+	case "BIT":
+		if length != "" && length != "1" {
+			return "int"
+		}
+
+		return "bool"
+	case "BOOL", "BOOLEAN":
+		return "bool"
+	case "DATE":
+		return "date"
+	case "DATETIME", "TIMESTAMP", "TIMESTAMPTZ":
+		return "time"
+	case "JSON", "JSONB":
+		return "json"
+	default:
+		return "string"
+	}
+}
+
+// InspectDynamoDbSchema connects to the table of the config, and returns the schema of the dynamodb input plugin
+// inferred from the items scanned up to samples.
+// The attributes of different types in the items (and sets, binaries) are `any`, and the empty lists are `any` since the items are unknown.
+func InspectDynamoDbSchema(ctx context.Context, config InputPluginDynamoDbConfig, samples int) (map[string]InputPluginDynamoDbConfigSchemaColumn, error) {
+	if config.Table == "" {
+		return nil, fmt.Errorf("table is required")
+	}
+	if samples <= 0 {
+		return nil, fmt.Errorf("samples must be positive: %v", samples)
+	}
+
+	client, err := config.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	items := []map[string]types.AttributeValue{}
+	var lastEvaluatedKey map[string]types.AttributeValue
+	for len(items) < samples {
+		resp, err := client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:         aws.String(config.Table),
+			Limit:             aws.Int32(int32(min(samples-len(items), 1000))),
+			ExclusiveStartKey: lastEvaluatedKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan table: %v (error: %v)", config.Table, err)
+		}
+
+		items = append(items, resp.Items...)
+
+		if resp.LastEvaluatedKey == nil {
+			break
+		}
+		lastEvaluatedKey = resp.LastEvaluatedKey
+	}
+
+	return inspectDynamoDbItems(items), nil
+}
+
+func inspectDynamoDbItems(items []map[string]types.AttributeValue) map[string]InputPluginDynamoDbConfigSchemaColumn {
+	schema := map[string]InputPluginDynamoDbConfigSchemaColumn{}
+	for _, item := range items {
+		for k, v := range item {
+			schema[k] = mergeDynamoDbColumn(schema[k], inspectDynamoDbValue(v))
+		}
+	}
+
+	for k, c := range schema {
+		schema[k] = c.complete()
+	}
+
+	return schema
+}
+
+// inspectDynamoDbValue returns the column for the value. The type is empty for NULL, and the items are nil for an empty list.
+func inspectDynamoDbValue(v types.AttributeValue) InputPluginDynamoDbConfigSchemaColumn {
+	switch v := v.(type) {
+	case *types.AttributeValueMemberNULL:
+		return InputPluginDynamoDbConfigSchemaColumn{}
+	case *types.AttributeValueMemberS:
+		return InputPluginDynamoDbConfigSchemaColumn{Type: "string"}
+	case *types.AttributeValueMemberN:
+		return InputPluginDynamoDbConfigSchemaColumn{Type: "number"}
+	case *types.AttributeValueMemberBOOL:
+		return InputPluginDynamoDbConfigSchemaColumn{Type: "boolean"}
+	case *types.AttributeValueMemberM:
+		column := InputPluginDynamoDbConfigSchemaColumn{Type: "object", Properties: map[string]InputPluginDynamoDbConfigSchemaColumn{}}
+		for k, v := range v.Value {
+			column.Properties[k] = inspectDynamoDbValue(v)
+		}
+
+		return column
+	case *types.AttributeValueMemberL:
+		column := InputPluginDynamoDbConfigSchemaColumn{Type: "array"}
+		for _, item := range v.Value {
+			items := inspectDynamoDbValue(item)
+			if column.Items != nil {
+				items = mergeDynamoDbColumn(*column.Items, items)
+			}
+			column.Items = &items
+		}
+
+		return column
+	default:
+		return InputPluginDynamoDbConfigSchemaColumn{Type: "any"}
+	}
+}
+
+// mergeDynamoDbColumn returns the column which accepts the values of both columns.
+func mergeDynamoDbColumn(a InputPluginDynamoDbConfigSchemaColumn, b InputPluginDynamoDbConfigSchemaColumn) InputPluginDynamoDbConfigSchemaColumn {
+	if a.Type == "" {
+		return b
+	}
+	if b.Type == "" {
+		return a
+	}
+	if a.Type != b.Type {
+		return InputPluginDynamoDbConfigSchemaColumn{Type: "any"}
+	}
+
+	switch a.Type {
+	case "object":
+		properties := map[string]InputPluginDynamoDbConfigSchemaColumn{}
+		for k, p := range a.Properties {
+			properties[k] = p
+		}
+		for k, p := range b.Properties {
+			properties[k] = mergeDynamoDbColumn(properties[k], p)
+		}
+
+		return InputPluginDynamoDbConfigSchemaColumn{Type: "object", Properties: properties}
+	case "array":
+		if a.Items == nil {
+			return b
+		}
+		if b.Items == nil {
+			return a
+		}
+
+		items := mergeDynamoDbColumn(*a.Items, *b.Items)
+		return InputPluginDynamoDbConfigSchemaColumn{Type: "array", Items: &items}
+	default:
+		return a
+	}
+}
+
+// complete returns the column which can be used in the config: NULLs, arrays of unknown items and empty objects are `any`.
+func (c InputPluginDynamoDbConfigSchemaColumn) complete() InputPluginDynamoDbConfigSchemaColumn {
+	switch {
+	case c.Type == "" || c.Type == "array" && c.Items == nil || c.Type == "object" && len(c.Properties) == 0:
+		return InputPluginDynamoDbConfigSchemaColumn{Type: "any"}
+	case c.Type == "array":
+		items := c.Items.complete()
+		return InputPluginDynamoDbConfigSchemaColumn{Type: "array", Items: &items}
+	case c.Type == "object":
+		properties := map[string]InputPluginDynamoDbConfigSchemaColumn{}
+		for k, p := range c.Properties {
+			properties[k] = p.complete()
+		}
+
+		return InputPluginDynamoDbConfigSchemaColumn{Type: "object", Properties: properties}
+	default:
+		return c
+	}
+}
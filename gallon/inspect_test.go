@@ -0,0 +1,104 @@
+package gallon
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sql_column_type(t *testing.T) {
+	tests := map[string]string{
+		"INT":              "int",
+		"UNSIGNED BIGINT":  "int",
+		"int8":             "int",
+		"DECIMAL":          "decimal",
+		"NUMERIC":          "decimal",
+		"DOUBLE":           "float",
+		"DOUBLE PRECISION": "float",
+		"BOOL":             "bool",
+		"BIT":              "bool",
+		"BIT(1)":           "bool",
+		"BIT(8)":           "int",
+		"FLOAT":            "float",
+		"REAL":             "float",
+		"DATE":             "date",
+		"DATETIME":         "time",
+		"TIMESTAMPTZ":      "time",
+		"JSON":             "json",
+		"JSONB":            "json",
+		"VARCHAR":          "string",
+		"TIME":             "string",
+		"":                 "string",
+	}
+
+	for databaseType, expected := range tests {
+		assert.Equal(t, expected, sqlColumnType(databaseType), databaseType)
+	}
+}
+
+func Test_sql_column_type_values(t *testing.T) {
+	// the values are the ones returned by the mysql driver
+	tests := []struct {
+		databaseType string
+		value        any
+		expected     any
+	}{
+		{databaseType: "FLOAT", value: float32(1.1), expected: 1.1},
+		{databaseType: "REAL", value: 1.1, expected: 1.1},
+		{databaseType: "BIT(1)", value: []byte{1}, expected: true},
+		{databaseType: "BIT(8)", value: []byte{0xaa}, expected: int64(170)},
+		{databaseType: "BIT(16)", value: []byte{0x01, 0x00}, expected: int64(256)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.databaseType, func(t *testing.T) {
+			column := InputPluginSqlConfigSchemaColumn{Type: sqlColumnType(tt.databaseType)}
+
+			value, err := column.getValue(tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func Test_inspect_dynamodb_items(t *testing.T) {
+	items := []map[string]types.AttributeValue{
+		{
+			"id":   &types.AttributeValueMemberS{Value: "1"},
+			"age":  &types.AttributeValueMemberN{Value: "20"},
+			"memo": &types.AttributeValueMemberNULL{Value: true},
+			"tags": &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+			"address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"city": &types.AttributeValueMemberS{Value: "Tokyo"},
+			}},
+			"score": &types.AttributeValueMemberN{Value: "1.5"},
+			"roles": &types.AttributeValueMemberSS{Value: []string{"admin"}},
+		},
+		{
+			"id":   &types.AttributeValueMemberS{Value: "2"},
+			"tags": &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "a"}}},
+			"address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"zip": &types.AttributeValueMemberS{Value: "100-0001"},
+			}},
+			"score":    &types.AttributeValueMemberS{Value: "unknown"},
+			"verified": &types.AttributeValueMemberBOOL{Value: true},
+			"extra":    &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{}},
+		},
+	}
+
+	assert.Equal(t, map[string]InputPluginDynamoDbConfigSchemaColumn{
+		"id":   {Type: "string"},
+		"age":  {Type: "number"},
+		"memo": {Type: "any"},
+		"tags": {Type: "array", Items: &InputPluginDynamoDbConfigSchemaColumn{Type: "string"}},
+		"address": {Type: "object", Properties: map[string]InputPluginDynamoDbConfigSchemaColumn{
+			"city": {Type: "string"},
+			"zip":  {Type: "string"},
+		}},
+		"score":    {Type: "any"},
+		"roles":    {Type: "any"},
+		"verified": {Type: "boolean"},
+		"extra":    {Type: "any"},
+	}, inspectDynamoDbItems(items))
+}
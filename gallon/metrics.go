@@ -0,0 +1,171 @@
+package gallon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics of migrations. They are updated whether or not they are registered, and exposed by RegisterMetrics.
+var (
+	metricExtractedRecords = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "gallon",
+			Name:      "extracted_records_total",
+			Help:      "Number of records extracted by the input plugin.",
+		},
+		[]string{"plugin", "table"},
+	)
+	metricLoadedRecords = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "gallon",
+			Name:      "loaded_records_total",
+			Help:      "Number of records loaded by the output plugin.",
+		},
+		[]string{"plugin", "table"},
+	)
+	metricDeadLetterRecords = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "gallon",
+			Name:      "dead_letter_records_total",
+			Help:      "Number of rejected records sent to the dead-letter output.",
+		},
+		[]string{"stage"},
+	)
+	metricFailedRecords = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "gallon",
+			Name:      "failed_records_total",
+			Help:      "Number of non-fatal errors (rejected records) reported by the plugin.",
+		},
+		[]string{"stage", "plugin", "table"},
+	)
+	metricBatchDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "gallon",
+			Name:      "batch_duration_seconds",
+			Help:      "Time to extract, transform or load a batch.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		},
+		[]string{"stage", "plugin", "table"},
+	)
+	metricBacklogBatches = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "gallon",
+			Name:      "backlog_batches",
+			Help:      "Number of batches extracted but not yet handed to all the output plugins.",
+		},
+		[]string{"plugin", "table"},
+	)
+	metricBigQueryJobDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "gallon",
+			Name:      "bigquery_job_duration_seconds",
+			Help:      "Time from starting a BigQuery job until it is done.",
+			Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+		},
+		[]string{"job", "table"},
+	)
+)
+
+// RegisterMetrics registers the Prometheus metrics of gallon to the registerer (e.g. prometheus.DefaultRegisterer).
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	collectors := []prometheus.Collector{
+		metricExtractedRecords,
+		metricLoadedRecords,
+		metricDeadLetterRecords,
+		metricFailedRecords,
+		metricBatchDuration,
+		metricBacklogBatches,
+		metricBigQueryJobDuration,
+	}
+
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			var alreadyRegistered prometheus.AlreadyRegisteredError
+			if errors.As(err, &alreadyRegistered) {
+				continue
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// metricLabeler is implemented by the built-in plugins to label their metrics.
+type metricLabeler interface {
+	metricLabels() (plugin string, table string)
+}
+
+// metricLabelsOf returns the labels of the plugin. Other plugins are labelled by their type name.
+func metricLabelsOf(plugin any) (string, string) {
+	if labeler, ok := plugin.(metricLabeler); ok {
+		return labeler.metricLabels()
+	}
+
+	return fmt.Sprintf("%T", plugin), ""
+}
+
+type deadLetterContextKey struct{}
+
+// withDeadLetter marks the context passed to Gallon.DeadLetter, so that the dead letters are not counted as loaded records.
+func withDeadLetter(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deadLetterContextKey{}, true)
+}
+
+// addLoadedRecords counts the records loaded by the output plugin, unless it is the dead-letter output (See metricDeadLetterRecords).
+func addLoadedRecords(ctx context.Context, plugin metricLabeler, n int) {
+	if ctx.Value(deadLetterContextKey{}) != nil {
+		return
+	}
+
+	metricLoadedRecords.WithLabelValues(plugin.metricLabels()).Add(float64(n))
+}
+
+// observeBatchDuration records the time since startedAt as the duration of a batch processed by the plugin.
+func observeBatchDuration(stage GallonStage, plugin any, startedAt time.Time) {
+	name, table := metricLabelsOf(plugin)
+	metricBatchDuration.WithLabelValues(string(stage), name, table).Observe(time.Since(startedAt).Seconds())
+}
+
+// backlogMetric tracks the backlog of a migration. When the migration finishes, the batches left in the pipeline
+// are removed from the gauge and the later changes are ignored.
+type backlogMetric struct {
+	mu     sync.Mutex
+	gauge  prometheus.Gauge
+	count  int
+	closed bool
+}
+
+func newBacklogMetric(plugin any) *backlogMetric {
+	return &backlogMetric{
+		gauge: metricBacklogBatches.WithLabelValues(metricLabelsOf(plugin)),
+	}
+}
+
+func (b *backlogMetric) add(delta int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.count += delta
+	b.gauge.Add(float64(delta))
+}
+
+func (b *backlogMetric) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.gauge.Sub(float64(b.count))
+	b.count = 0
+	b.closed = true
+}
@@ -0,0 +1,118 @@
+package gallon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_metrics(t *testing.T) {
+	output, err := NewOutputPluginStdoutFromConfig([]byte(`
+format: json
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	output.deserialize = func(i GallonRecord) ([]byte, error) {
+		id, _ := i.Get("id")
+		if id == "2" {
+			return nil, errors.New("error")
+		}
+
+		return json.Marshal(&i)
+	}
+
+	page := []GallonRecord{}
+	for _, id := range []string{"1", "2", "3"} {
+		r := NewGallonRecord()
+		r.Set("id", id)
+
+		page = append(page, r)
+	}
+
+	input := NewInputPluginStub([][]GallonRecord{page, page})
+	inputPlugin, inputTable := metricLabelsOf(input)
+
+	extracted := testutil.ToFloat64(metricExtractedRecords.WithLabelValues(inputPlugin, inputTable))
+	loaded := testutil.ToFloat64(metricLoadedRecords.WithLabelValues("stdout", ""))
+	failed := testutil.ToFloat64(metricFailedRecords.WithLabelValues("load", "stdout", ""))
+
+	g := Gallon{
+		Logger: logger,
+		Input:  input,
+		Output: output,
+	}
+
+	if err := g.Run(context.Background()); err != nil {
+		t.Errorf("Could not run command: %s", err)
+	}
+
+	assert.Equal(t, "*gallon.InputPluginStub", inputPlugin)
+	assert.Equal(t, 6.0, testutil.ToFloat64(metricExtractedRecords.WithLabelValues(inputPlugin, inputTable))-extracted)
+	assert.Equal(t, 4.0, testutil.ToFloat64(metricLoadedRecords.WithLabelValues("stdout", ""))-loaded)
+	assert.Equal(t, 2.0, testutil.ToFloat64(metricFailedRecords.WithLabelValues("load", "stdout", ""))-failed)
+	assert.Equal(t, 0.0, testutil.ToFloat64(metricBacklogBatches.WithLabelValues(inputPlugin, inputTable)))
+}
+
+func Test_dead_letter_metrics(t *testing.T) {
+	output, err := NewOutputPluginStdoutFromConfig([]byte(`
+format: json
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	output.deserialize = func(i GallonRecord) ([]byte, error) {
+		return nil, errors.New("error")
+	}
+
+	deadLetter, err := NewOutputPluginFileFromConfig([]byte(`
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual_dead_letter_metrics
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	buf := new(bytes.Buffer)
+	writer := bufio.NewWriter(buf)
+	deadLetter.newWriter = func() (io.WriteCloser, error) {
+		return NewNopWriteCloser(writer), nil
+	}
+
+	page := []GallonRecord{}
+	for _, id := range []string{"1", "2", "3"} {
+		r := NewGallonRecord()
+		r.Set("id", id)
+
+		page = append(page, r)
+	}
+
+	deadLetterPlugin, deadLetterTable := deadLetter.metricLabels()
+	loaded := testutil.ToFloat64(metricLoadedRecords.WithLabelValues(deadLetterPlugin, deadLetterTable))
+	deadLetters := testutil.ToFloat64(metricDeadLetterRecords.WithLabelValues("load"))
+
+	g := Gallon{
+		Logger:     logger,
+		Input:      NewInputPluginStub([][]GallonRecord{page}),
+		Output:     output,
+		DeadLetter: deadLetter,
+	}
+
+	if err := g.Run(context.Background()); err != nil {
+		t.Errorf("Could not run command: %s", err)
+	}
+
+	assert.Equal(t, 0.0, testutil.ToFloat64(metricLoadedRecords.WithLabelValues(deadLetterPlugin, deadLetterTable))-loaded)
+	assert.Equal(t, 3.0, testutil.ToFloat64(metricDeadLetterRecords.WithLabelValues("load"))-deadLetters)
+}
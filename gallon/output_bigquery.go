@@ -14,6 +14,8 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/api/option"
 	"gopkg.in/yaml.v3"
 )
@@ -27,6 +29,10 @@ type OutputPluginBigQuery struct {
 	schema               bigquery.Schema
 	deserialize          func(GallonRecord) ([]bigquery.Value, error)
 	deleteTemporaryTable bool
+	writeDisposition     bigquery.TableWriteDisposition
+	// loadedRows is the number of rows loaded by the load job, reported by Count
+	loadedRows *int64
+	ack        func()
 }
 
 func NewOutputPluginBigQuery(
@@ -37,6 +43,7 @@ func NewOutputPluginBigQuery(
 	schema bigquery.Schema,
 	deserialize func(GallonRecord) ([]bigquery.Value, error),
 	deleteTemporaryTable bool,
+	writeDisposition bigquery.TableWriteDisposition,
 ) *OutputPluginBigQuery {
 	return &OutputPluginBigQuery{
 		client:               client,
@@ -46,9 +53,12 @@ func NewOutputPluginBigQuery(
 		schema:               schema,
 		deserialize:          deserialize,
 		deleteTemporaryTable: deleteTemporaryTable,
+		writeDisposition:     writeDisposition,
 	}
 }
 
+var errSchemaRequired = errors.New("schema is required for bigquery plugin, unless the input plugin has the schema")
+
 type bqRecordWrapper map[string]bigquery.Value
 
 var _ bigquery.ValueSaver = bqRecordWrapper{}
@@ -58,6 +68,89 @@ func (w bqRecordWrapper) Save() (row map[string]bigquery.Value, insertID string,
 }
 
 var _ OutputPlugin = &OutputPluginBigQuery{}
+var _ ValidatingOutputPlugin = &OutputPluginBigQuery{}
+var _ AckOutputPlugin = &OutputPluginBigQuery{}
+var _ CountingOutputPlugin = &OutputPluginBigQuery{}
+var _ SchemaOutputPlugin = &OutputPluginBigQuery{}
+
+// SetSchema infers the BigQuery schema from the schema of the records, if the schema is not configured.
+func (p *OutputPluginBigQuery) SetSchema(schema GallonSchema) {
+	if p.schema != nil {
+		return
+	}
+
+	p.schema = inferBigQuerySchema(schema.Fields)
+	p.deserialize = newBigQueryDeserializer(p.schema)
+}
+
+// InferBigQuerySchema returns the BigQuery schema inferred from the schema of the records, as the bigquery output plugin does if `schema` is omitted.
+func InferBigQuerySchema(schema GallonSchema) bigquery.Schema {
+	return inferBigQuerySchema(schema.Fields)
+}
+
+// inferBigQuerySchema converts the fields into the BigQuery schema. All the columns are nullable.
+func inferBigQuerySchema(fields []GallonField) bigquery.Schema {
+	schema := bigquery.Schema{}
+	for _, f := range fields {
+		schema = append(schema, inferBigQueryField(f))
+	}
+
+	return schema
+}
+
+func inferBigQueryField(f GallonField) *bigquery.FieldSchema {
+	field := &bigquery.FieldSchema{Name: f.Name}
+
+	switch f.Type {
+	case GallonTypeString, GallonTypeNumber:
+		field.Type = bigquery.StringFieldType
+	case GallonTypeInt:
+		field.Type = bigquery.IntegerFieldType
+	case GallonTypeFloat:
+		field.Type = bigquery.FloatFieldType
+	case GallonTypeBool:
+		field.Type = bigquery.BooleanFieldType
+	case GallonTypeTime:
+		field.Type = bigquery.TimestampFieldType
+	case GallonTypeDate:
+		field.Type = bigquery.DateFieldType
+	case GallonTypeObject:
+		// RECORD requires the fields
+		if len(f.Fields) == 0 {
+			field.Type = bigquery.JSONFieldType
+			break
+		}
+
+		field.Type = bigquery.RecordFieldType
+		field.Schema = inferBigQuerySchema(f.Fields)
+	case GallonTypeArray:
+		// REPEATED requires the type of the items, and nested arrays are not supported by BigQuery
+		if f.Items == nil || f.Items.Type == GallonTypeArray {
+			field.Type = bigquery.JSONFieldType
+			break
+		}
+
+		items := inferBigQueryField(*f.Items)
+		if items.Type == bigquery.JSONFieldType {
+			field.Type = bigquery.JSONFieldType
+			break
+		}
+
+		field.Type = items.Type
+		field.Schema = items.Schema
+		field.Repeated = true
+	default:
+		field.Type = bigquery.JSONFieldType
+	}
+
+	return field
+}
+
+// SetAckHandler sets the function to acknowledge the batches. All the batches are acknowledged after the copy job into the destination table,
+// since the records are not loaded before that.
+func (p *OutputPluginBigQuery) SetAckHandler(ack func()) {
+	p.ack = ack
+}
 
 func (p *OutputPluginBigQuery) ReplaceLogger(logger logr.Logger) {
 	values := []any{}
@@ -80,6 +173,22 @@ func (p *OutputPluginBigQuery) Cleanup() error {
 	return p.client.Close()
 }
 
+func (p *OutputPluginBigQuery) metricLabels() (string, string) {
+	return "bigquery", fmt.Sprintf("%v.%v", p.datasetId, p.tableId)
+}
+
+func (p *OutputPluginBigQuery) Validate(record GallonRecord) error {
+	if p.deserialize == nil {
+		return errSchemaRequired
+	}
+
+	if _, err := p.deserialize(record); err != nil {
+		return fmt.Errorf("failed to deserialize: %v (error: %v)", record, err)
+	}
+
+	return nil
+}
+
 func (p *OutputPluginBigQuery) waitUntilTableCreation(ctx context.Context, tableId string) error {
 	timeout := time.After(300 * time.Second)
 	ticker := time.NewTicker(10 * time.Second)
@@ -103,17 +212,31 @@ func (p *OutputPluginBigQuery) Load(
 	messages chan []GallonRecord,
 	errs chan error,
 ) error {
+	if p.schema == nil {
+		return errSchemaRequired
+	}
+
 	temporaryTableId := fmt.Sprintf("LOAD_TEMP_%s_%s", p.tableId, uuid.New().String())
 	temporaryTable := p.client.Dataset(p.datasetId).Table(temporaryTableId)
+
+	_, createSpan := p.startSpan(ctx, "bigquery.createTemporaryTable", temporaryTableId)
 	if err := temporaryTable.Create(ctx, &bigquery.TableMetadata{
 		Schema: p.schema,
 	}); err != nil {
+		endSpan(createSpan, err)
 		return err
 	}
 
 	defer func() {
 		if p.deleteTemporaryTable {
-			if err := temporaryTable.Delete(ctx); err != nil {
+			// the temporary table is deleted even if the migration is cancelled
+			ctx := context.WithoutCancel(ctx)
+
+			_, deleteSpan := p.startSpan(ctx, "bigquery.deleteTemporaryTable", temporaryTableId)
+			err := temporaryTable.Delete(ctx)
+			endSpan(deleteSpan, err)
+
+			if err != nil {
 				p.logger.Error(err, "failed to delete temporary table", "tableId", temporaryTable.TableID)
 			} else {
 				p.logger.Info("temporary table deleted", "tableId", temporaryTable.TableID)
@@ -122,12 +245,15 @@ func (p *OutputPluginBigQuery) Load(
 	}()
 
 	if err := p.waitUntilTableCreation(ctx, temporaryTableId); err != nil {
+		endSpan(createSpan, err)
 		return err
 	}
+	endSpan(createSpan, nil)
 
 	p.logger.Info(fmt.Sprintf("created temporary table %v", temporaryTable.TableID))
 
 	loadedTotal := 0
+	receivedBatches := 0
 
 	temporaryJsonlFilePath := fmt.Sprintf("%v.jsonl.gz", temporaryTableId)
 	temporaryFile, err := os.CreateTemp("", temporaryJsonlFilePath)
@@ -152,11 +278,15 @@ loop:
 			if !ok {
 				break loop
 			}
+			receivedBatches++
 
+			batchStartedAt := time.Now()
+			_, batchSpan := startLoadBatchSpan(ctx, p, len(msgs))
+			written := 0
 			for _, msg := range msgs {
 				values, err := p.deserialize(msg)
 				if err != nil {
-					errs <- fmt.Errorf("failed to deserialize: %v, %v", msg, err)
+					errs <- NewRecordError(&msg, fmt.Errorf("failed to deserialize: %v, %v", msg, err))
 					continue
 				}
 
@@ -166,11 +296,16 @@ loop:
 				}
 
 				if err := temporaryFileWriter.Encode(mp); err != nil {
-					errs <- fmt.Errorf("failed to write to temporary file: %v, %v", values, err)
+					errs <- NewRecordError(&msg, fmt.Errorf("failed to write to temporary file: %v, %v", values, err))
 					continue
 				}
+				written++
 			}
 
+			endSpan(batchSpan, nil)
+			observeBatchDuration(GallonStageLoad, p, batchStartedAt)
+			addLoadedRecords(ctx, p, written)
+
 			if len(msgs) > 0 {
 				loadedTotal += len(msgs)
 				p.logger.Info(fmt.Sprintf("loaded %v rows", loadedTotal))
@@ -186,6 +321,12 @@ loop:
 		return fmt.Errorf("failed to close temporary file: %v", err)
 	}
 
+	// the destination table must not be overwritten with the records extracted so far
+	if err := ctx.Err(); err != nil {
+		p.logger.Info("cancelled before loading into the destination table")
+		return err
+	}
+
 	p.logger.Info(fmt.Sprintf("loading into %v", temporaryTable.TableID))
 
 	temporaryFile, err = os.Open(temporaryFile.Name())
@@ -209,16 +350,17 @@ loop:
 	loader := temporaryTable.LoaderFrom(source)
 	loader.WriteDisposition = bigquery.WriteTruncate
 
-	job, err := loader.Run(ctx)
+	loadStatus, err := p.runJob(ctx, "load", temporaryTableId, func(ctx context.Context) (*bigquery.Job, error) {
+		return loader.Run(ctx)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to load: %v", err)
-	}
-	status, err := job.Wait(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to wait for job: %v", err)
+		return err
 	}
-	if err := status.Err(); err != nil {
-		return fmt.Errorf("job failed: %v (details: %v)", err, status.Errors)
+
+	if loadStatus.Statistics != nil {
+		if stats, ok := loadStatus.Statistics.Details.(*bigquery.LoadStatistics); ok {
+			p.loadedRows = &stats.OutputRows
+		}
 	}
 
 	p.logger.Info(fmt.Sprintf("loaded into %v", temporaryTable.TableID))
@@ -227,38 +369,93 @@ loop:
 	// copier := p.client.Dataset(p.datasetId).Table(p.tableId).CopierFrom(temporaryTable)
 
 	copier := p.client.Query(fmt.Sprintf("SELECT * FROM `%v.%v`", temporaryTable.DatasetID, temporaryTable.TableID))
-	copier.WriteDisposition = bigquery.WriteTruncate
+	copier.WriteDisposition = p.writeDisposition
 	copier.Dst = p.client.Dataset(p.datasetId).Table(p.tableId)
 
-	job, err = copier.Run(ctx)
+	if _, err := p.runJob(ctx, "copy", temporaryTableId, func(ctx context.Context) (*bigquery.Job, error) {
+		return copier.Run(ctx)
+	}); err != nil {
+		return err
+	}
+
+	p.logger.Info(fmt.Sprintf("copied from %v to %v", temporaryTable.TableID, p.tableId))
+
+	if p.ack != nil {
+		for range receivedBatches {
+			p.ack()
+		}
+	}
+
+	return nil
+}
+
+// Count returns the number of rows loaded by the load job in the last Load.
+func (p *OutputPluginBigQuery) Count(ctx context.Context) (int, error) {
+	if p.loadedRows == nil {
+		return 0, fmt.Errorf("no statistics of the load job: %v.%v", p.datasetId, p.tableId)
+	}
+
+	return int(*p.loadedRows), nil
+}
+
+// runJob runs a BigQuery job and waits for it, recording the span and the duration as the job (`load` or `copy`).
+func (p *OutputPluginBigQuery) runJob(
+	ctx context.Context,
+	job string,
+	temporaryTableId string,
+	run func(context.Context) (*bigquery.Job, error),
+) (status *bigquery.JobStatus, err error) {
+	ctx, span := p.startSpan(ctx, "bigquery."+job, temporaryTableId)
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	startedAt := time.Now()
+	j, err := run(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to copy: %v", err)
+		return nil, fmt.Errorf("failed to %v: %v", job, err)
 	}
-	status, err = job.Wait(ctx)
+	span.SetAttributes(attribute.String("bigquery.job_id", j.ID()))
+
+	status, err = j.Wait(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to wait for job: %v", err)
+		return nil, fmt.Errorf("failed to wait for job: %v", err)
 	}
+
+	_, table := p.metricLabels()
+	metricBigQueryJobDuration.WithLabelValues(job, table).Observe(time.Since(startedAt).Seconds())
+
 	if err := status.Err(); err != nil {
-		return fmt.Errorf("job failed: %v", err)
+		return nil, fmt.Errorf("job failed: %v (details: %v)", err, status.Errors)
 	}
 
-	p.logger.Info(fmt.Sprintf("copied from %v to %v", temporaryTable.TableID, p.tableId))
+	return status, nil
+}
 
-	return nil
+func (p *OutputPluginBigQuery) startSpan(ctx context.Context, name string, temporaryTableId string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("bigquery.dataset", p.datasetId),
+		attribute.String("bigquery.table", p.tableId),
+		attribute.String("bigquery.temporary_table", temporaryTableId),
+	))
 }
 
 type OutputPluginBigQueryConfig struct {
-	ProjectId            string                                                                `yaml:"projectId"`
-	DatasetId            string                                                                `yaml:"datasetId"`
-	TableId              string                                                                `yaml:"tableId"`
+	ProjectId string `yaml:"projectId"`
+	DatasetId string `yaml:"datasetId"`
+	TableId   string `yaml:"tableId"`
+	// Location is not used, since the jobs run in the location of the dataset. It is accepted for the configs which have it.
+	Location             string                                                                `yaml:"location"`
 	Endpoint             *string                                                               `yaml:"endpoint"`
 	Schema               orderedmap.OrderedMap[string, OutputPluginBigQueryConfigSchemaColumn] `yaml:"schema"`
 	DeleteTemporaryTable *bool                                                                 `yaml:"deleteTemporaryTable"`
+	Append               bool                                                                  `yaml:"append"`
 }
 
 type OutputPluginBigQueryConfigSchemaColumn struct {
-	Type   string                                                                `yaml:"type"`
-	Fields orderedmap.OrderedMap[string, OutputPluginBigQueryConfigSchemaColumn] `yaml:"fields,omitempty"`
+	Type     string                                                                `yaml:"type"`
+	Fields   orderedmap.OrderedMap[string, OutputPluginBigQueryConfigSchemaColumn] `yaml:"fields,omitempty"`
+	Repeated bool                                                                  `yaml:"repeated,omitempty"`
 }
 
 func NewOutputPluginBigQueryFromConfig(configYml []byte) (*OutputPluginBigQuery, error) {
@@ -280,9 +477,16 @@ func NewOutputPluginBigQueryFromConfig(configYml []byte) (*OutputPluginBigQuery,
 		return nil, err
 	}
 
-	schema, err := getSchemaFromConfig(config.Schema)
-	if err != nil {
-		return nil, err
+	// the schema is inferred from the input (See SetSchema) if it is omitted
+	var schema bigquery.Schema
+	var deserialize func(GallonRecord) ([]bigquery.Value, error)
+	if config.Schema.Len() > 0 {
+		schema, err = getSchemaFromConfig(config.Schema)
+		if err != nil {
+			return nil, err
+		}
+
+		deserialize = newBigQueryDeserializer(schema)
 	}
 
 	deleteTemporaryTable := true
@@ -290,95 +494,137 @@ func NewOutputPluginBigQueryFromConfig(configYml []byte) (*OutputPluginBigQuery,
 		deleteTemporaryTable = *config.DeleteTemporaryTable
 	}
 
+	writeDisposition := bigquery.WriteTruncate
+	if config.Append {
+		writeDisposition = bigquery.WriteAppend
+	}
+
 	return NewOutputPluginBigQuery(
 		client,
 		config.Endpoint,
 		config.DatasetId,
 		config.TableId,
 		schema,
-		func(item GallonRecord) ([]bigquery.Value, error) {
-			values := []bigquery.Value{}
-			for _, v := range schema {
-				value, ok := item.Get(v.Name)
-				if !ok {
-					values = append(values, nil)
-					continue
-				}
-
-				if v.Type == bigquery.RecordFieldType {
-					if value == nil {
-						values = append(values, nil)
-						continue
-					}
-
-					recordValue, err := deserializeRecord(value.(map[string]any), v.Schema)
-					if err != nil {
-						return nil, err
-					}
-
-					values = append(values, recordValue)
-				} else if v.Type == bigquery.StringFieldType {
-					// If the field is a string, and the value is a JSON object, we need to deserialize it
-					switch value.(type) {
-					case string:
-						values = append(values, value)
-					case nil:
-						values = append(values, nil)
-					default:
-						jsonBytes, err := json.Marshal(value)
-						if err != nil {
-							return nil, err
-						}
-
-						values = append(values, string(jsonBytes))
-					}
-				} else {
-					values = append(values, value)
-				}
-			}
-			return values, nil
-		},
+		deserialize,
 		deleteTemporaryTable,
+		writeDisposition,
 	), nil
 }
 
-func deserializeRecord(data map[string]any, schema bigquery.Schema) (map[string]bigquery.Value, error) {
-	values := map[string]bigquery.Value{}
-	for _, field := range schema {
-		value := data[field.Name]
-		if field.Type == bigquery.RecordFieldType {
-			if value == nil {
-				values[field.Name] = nil
+// newBigQueryDeserializer converts a record into the values of the columns in the schema.
+func newBigQueryDeserializer(schema bigquery.Schema) func(GallonRecord) ([]bigquery.Value, error) {
+	return func(item GallonRecord) ([]bigquery.Value, error) {
+		values := []bigquery.Value{}
+		for _, v := range schema {
+			value, ok := item.Get(v.Name)
+			if !ok {
+				values = append(values, nil)
 				continue
 			}
-			recordValue, err := deserializeRecord(value.(map[string]any), field.Schema)
+
+			// If the column is a string, and the value is not (e.g. a JSON object), we need to serialize it.
+			// The values of the nested fields are passed as they are.
+			if _, ok := value.(string); v.Type == bigquery.StringFieldType && !v.Repeated && value != nil && !ok {
+				jsonBytes, err := json.Marshal(value)
+				if err != nil {
+					return nil, err
+				}
+
+				values = append(values, string(jsonBytes))
+				continue
+			}
+
+			converted, err := deserializeValue(value, v)
 			if err != nil {
 				return nil, err
 			}
-			values[field.Name] = recordValue
-		} else {
-			values[field.Name] = value
+
+			values = append(values, converted)
 		}
+		return values, nil
+	}
+}
+
+func deserializeValue(value any, field *bigquery.FieldSchema) (bigquery.Value, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	if field.Repeated {
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("value is not array: %v", value)
+		}
+
+		item := *field
+		item.Repeated = false
+
+		values := []bigquery.Value{}
+		for _, v := range items {
+			converted, err := deserializeValue(v, &item)
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, converted)
+		}
+
+		return values, nil
+	}
+
+	switch field.Type {
+	case bigquery.RecordFieldType:
+		data, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("value is not record: %v", value)
+		}
+
+		return deserializeRecord(data, field.Schema)
+	default:
+		return value, nil
+	}
+}
+
+func deserializeRecord(data map[string]any, schema bigquery.Schema) (map[string]bigquery.Value, error) {
+	values := map[string]bigquery.Value{}
+	for _, field := range schema {
+		value, err := deserializeValue(data[field.Name], field)
+		if err != nil {
+			return nil, err
+		}
+		values[field.Name] = value
 	}
 	return values, nil
 }
 
+// bigQueryFieldTypes are the types of the schema columns. They are case-insensitive in the config.
+var bigQueryFieldTypes = []bigquery.FieldType{
+	bigquery.StringFieldType,
+	bigquery.IntegerFieldType,
+	bigquery.FloatFieldType,
+	bigquery.BooleanFieldType,
+	bigquery.TimestampFieldType,
+	bigquery.DateFieldType,
+	bigquery.RecordFieldType,
+	bigquery.JSONFieldType,
+}
+
+// bigQueryFieldTypeAliases are the names of the types in GoogleSQL, which are accepted in the config as well.
+var bigQueryFieldTypeAliases = map[string]bigquery.FieldType{
+	"INT64":   bigquery.IntegerFieldType,
+	"FLOAT64": bigquery.FloatFieldType,
+	"BOOL":    bigquery.BooleanFieldType,
+}
+
 func getType(t string) (bigquery.FieldType, error) {
-	switch strings.ToUpper(t) {
-	case "STRING":
-		return bigquery.StringFieldType, nil
-	case "INTEGER":
-		return bigquery.IntegerFieldType, nil
-	case "FLOAT":
-		return bigquery.FloatFieldType, nil
-	case "BOOLEAN":
-		return bigquery.BooleanFieldType, nil
-	case "TIMESTAMP":
-		return bigquery.TimestampFieldType, nil
-	case "RECORD":
-		return bigquery.RecordFieldType, nil
-	case "JSON":
-		return bigquery.JSONFieldType, nil
+	for _, fieldType := range bigQueryFieldTypes {
+		if string(fieldType) == strings.ToUpper(t) {
+			return fieldType, nil
+		}
+	}
+
+	if fieldType, ok := bigQueryFieldTypeAliases[strings.ToUpper(t)]; ok {
+		return fieldType, nil
 	}
 
 	return "", errors.New("unknown type: " + t)
@@ -395,8 +641,9 @@ func getSchemaFromConfig(config orderedmap.OrderedMap[string, OutputPluginBigQue
 		}
 
 		field := &bigquery.FieldSchema{
-			Name: name,
-			Type: t,
+			Name:     name,
+			Type:     t,
+			Repeated: column.Repeated,
 		}
 
 		if t == bigquery.RecordFieldType {
@@ -0,0 +1,186 @@
+package gallon
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_infer_bigquery_schema(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   bigquery.Schema
+	}{
+		{
+			name: "sql",
+			config: `
+in:
+  type: sql
+  table: users
+  schema:
+    id:
+      type: int
+    name:
+      type: string
+      rename: user_name
+    score:
+      type: decimal
+    active:
+      type: bool
+    birthday:
+      type: date
+    created_at:
+      type: time
+    updated_at:
+      type: int
+      transforms:
+        - type: time
+    metadata:
+      type: json
+`,
+			want: bigquery.Schema{
+				{Name: "id", Type: bigquery.IntegerFieldType},
+				{Name: "user_name", Type: bigquery.StringFieldType},
+				{Name: "score", Type: bigquery.FloatFieldType},
+				{Name: "active", Type: bigquery.BooleanFieldType},
+				{Name: "birthday", Type: bigquery.DateFieldType},
+				{Name: "created_at", Type: bigquery.TimestampFieldType},
+				{Name: "updated_at", Type: bigquery.TimestampFieldType},
+				{Name: "metadata", Type: bigquery.JSONFieldType},
+			},
+		},
+		{
+			name: "dynamodb",
+			config: `
+in:
+  type: dynamodb
+  table: users
+  schema:
+    id:
+      type: string
+    age:
+      type: number
+    verified:
+      type: boolean
+      rename: is_verified
+    address:
+      type: object
+      properties:
+        city:
+          type: string
+        zip:
+          type: string
+    tags:
+      type: array
+      items:
+        type: string
+    histories:
+      type: array
+      items:
+        type: object
+        properties:
+          at:
+            type: string
+    extra:
+      type: any
+`,
+			want: bigquery.Schema{
+				{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+					{Name: "city", Type: bigquery.StringFieldType},
+					{Name: "zip", Type: bigquery.StringFieldType},
+				}},
+				{Name: "age", Type: bigquery.StringFieldType},
+				{Name: "extra", Type: bigquery.JSONFieldType},
+				{Name: "histories", Type: bigquery.RecordFieldType, Repeated: true, Schema: bigquery.Schema{
+					{Name: "at", Type: bigquery.StringFieldType},
+				}},
+				{Name: "id", Type: bigquery.StringFieldType},
+				{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+				{Name: "is_verified", Type: bigquery.BooleanFieldType},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config GallonConfig[yaml.Node, any]
+			if err := yaml.Unmarshal([]byte(tt.config), &config); err != nil {
+				t.Fatalf("Could not parse config: %s", err)
+			}
+
+			schema, err := inputSchema(&config.In)
+			if err != nil {
+				t.Fatalf("Could not get schema: %s", err)
+			}
+
+			output := NewOutputPluginBigQuery(nil, nil, "test", "users", nil, nil, true, bigquery.WriteTruncate)
+			output.SetSchema(*schema)
+
+			assert.Equal(t, tt.want, output.schema)
+		})
+	}
+}
+
+func Test_bigquery_schema_configured(t *testing.T) {
+	configured := bigquery.Schema{{Name: "id", Type: bigquery.StringFieldType}}
+	output := NewOutputPluginBigQuery(nil, nil, "test", "users", configured, newBigQueryDeserializer(configured), true, bigquery.WriteTruncate)
+
+	output.SetSchema(GallonSchema{Fields: []GallonField{{Name: "id", Type: GallonTypeInt}}})
+
+	assert.Equal(t, configured, output.schema)
+}
+
+func Test_bigquery_deserialize_repeated(t *testing.T) {
+	schema := inferBigQuerySchema([]GallonField{
+		{Name: "tags", Type: GallonTypeArray, Items: &GallonField{Type: GallonTypeString}},
+		{Name: "histories", Type: GallonTypeArray, Items: &GallonField{Type: GallonTypeObject, Fields: []GallonField{
+			{Name: "at", Type: GallonTypeString},
+		}}},
+	})
+
+	record := NewGallonRecord()
+	record.Set("tags", []any{"a", "b"})
+	record.Set("histories", []any{map[string]any{"at": "2024-01-01"}, map[string]any{}})
+
+	values, err := newBigQueryDeserializer(schema)(record)
+	assert.NoError(t, err)
+	assert.Equal(t, []bigquery.Value{
+		[]bigquery.Value{"a", "b"},
+		[]bigquery.Value{
+			map[string]bigquery.Value{"at": "2024-01-01"},
+			map[string]bigquery.Value{"at": nil},
+		},
+	}, values)
+
+	record.Set("tags", "not an array")
+	_, err = newBigQueryDeserializer(schema)(record)
+	assert.Error(t, err)
+}
+
+func Test_bigquery_deserialize_string(t *testing.T) {
+	schema := bigquery.Schema{
+		{Name: "memo", Type: bigquery.StringFieldType},
+		{Name: "code", Type: bigquery.StringFieldType},
+		{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "zip", Type: bigquery.StringFieldType},
+			{Name: "verified", Type: bigquery.StringFieldType},
+		}},
+	}
+
+	record := NewGallonRecord()
+	record.Set("memo", map[string]any{"a": 1.0})
+	record.Set("code", 1.5)
+	record.Set("address", map[string]any{"zip": 1000001.0, "verified": true})
+
+	values, err := newBigQueryDeserializer(schema)(record)
+	assert.NoError(t, err)
+	// the non-string values of the columns are serialized in JSON, while the ones of the nested fields are passed as they are
+	assert.Equal(t, []bigquery.Value{
+		`{"a":1}`,
+		"1.5",
+		map[string]bigquery.Value{"zip": 1000001.0, "verified": true},
+	}, values)
+}
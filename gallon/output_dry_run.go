@@ -0,0 +1,91 @@
+package gallon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// ValidatingOutputPlugin is an OutputPlugin which can check a record without loading it.
+type ValidatingOutputPlugin interface {
+	OutputPlugin
+
+	// Validate returns an error if the record cannot be converted for the destination (e.g. a type mismatch).
+	// It must not have any side effects.
+	Validate(record GallonRecord) error
+}
+
+// OutputPluginDryRun validates the records with the output plugin (See ValidatingOutputPlugin) instead of loading them.
+// Invalid records are sent to the errs channel as RecordError. Nothing is written to the destination.
+type OutputPluginDryRun struct {
+	logger logr.Logger
+	output OutputPlugin
+}
+
+func NewOutputPluginDryRun(output OutputPlugin) *OutputPluginDryRun {
+	return &OutputPluginDryRun{
+		output: output,
+	}
+}
+
+var _ OutputPlugin = &OutputPluginDryRun{}
+var _ SchemaOutputPlugin = &OutputPluginDryRun{}
+
+// SetSchema passes the schema to the output, since the validation may depend on it.
+func (p *OutputPluginDryRun) SetSchema(schema GallonSchema) {
+	if output, ok := p.output.(SchemaOutputPlugin); ok {
+		output.SetSchema(schema)
+	}
+}
+
+func (p *OutputPluginDryRun) ReplaceLogger(logger logr.Logger) {
+	p.logger = logger
+	p.output.ReplaceLogger(logger)
+}
+
+func (p *OutputPluginDryRun) Cleanup() error {
+	return p.output.Cleanup()
+}
+
+func (p *OutputPluginDryRun) metricLabels() (string, string) {
+	return metricLabelsOf(p.output)
+}
+
+func (p *OutputPluginDryRun) Load(
+	ctx context.Context,
+	messages chan []GallonRecord,
+	errs chan error,
+) error {
+	validator, ok := p.output.(ValidatingOutputPlugin)
+	if !ok {
+		p.logger.Info(fmt.Sprintf("output plugin %T does not support validation, the records are not checked", p.output))
+	}
+
+	validatedTotal := 0
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case msgs, ok := <-messages:
+			if !ok {
+				break loop
+			}
+
+			if validator != nil {
+				for _, msg := range msgs {
+					if err := validator.Validate(msg); err != nil {
+						errs <- NewRecordError(&msg, err)
+					}
+				}
+			}
+
+			validatedTotal += len(msgs)
+			p.logger.Info(fmt.Sprintf("validated %v records", validatedTotal))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package gallon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_dry_run(t *testing.T) {
+	output, err := NewOutputPluginFileFromConfig([]byte(`
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	output.newWriter = func() (io.WriteCloser, error) {
+		t.Errorf("file must not be created in dry run")
+		return nil, errors.New("file must not be created in dry run")
+	}
+	output.deserialize = func(i GallonRecord) ([]byte, error) {
+		id, _ := i.Get("id")
+		if id == "2" || id == "5" {
+			return nil, errors.New("error")
+		}
+
+		return json.Marshal(&i)
+	}
+
+	data := [][]GallonRecord{}
+	for _, ids := range [][]string{{"1", "2"}, {"3", "4", "5"}, {"6"}} {
+		page := []GallonRecord{}
+		for _, id := range ids {
+			r := NewGallonRecord()
+			r.Set("id", id)
+
+			page = append(page, r)
+		}
+
+		data = append(data, page)
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		extracted int
+		rejected  int
+	}{
+		{
+			name:      "all records",
+			limit:     0,
+			extracted: 6,
+			rejected:  2,
+		},
+		{
+			name:      "sample",
+			limit:     4,
+			extracted: 4,
+			rejected:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input InputPlugin = NewInputPluginStub(data)
+			if tt.limit > 0 {
+				input = NewInputPluginLimit(input, tt.limit)
+			}
+
+			g := Gallon{
+				Logger: logger,
+				Input:  input,
+				Output: NewOutputPluginDryRun(output),
+			}
+
+			result, err := g.RunWithResult(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.extracted, result.ExtractedRecords)
+			assert.Equal(t, tt.rejected, result.RejectedRecords)
+		})
+	}
+}
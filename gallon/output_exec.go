@@ -0,0 +1,180 @@
+package gallon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v3"
+)
+
+// OutputPluginExec loads the records into a command over JSON lines. See execMessage for the protocol.
+type OutputPluginExec struct {
+	logger logr.Logger
+	config ExecConfig
+	// schema is set by SetSchema
+	schema *GallonSchema
+}
+
+func NewOutputPluginExec(
+	config ExecConfig,
+) *OutputPluginExec {
+	return &OutputPluginExec{
+		config: config,
+	}
+}
+
+var _ OutputPlugin = &OutputPluginExec{}
+var _ SchemaOutputPlugin = &OutputPluginExec{}
+
+// SetSchema makes the plugin send the schema message after the init message.
+func (p *OutputPluginExec) SetSchema(schema GallonSchema) {
+	p.schema = &schema
+}
+
+func (p *OutputPluginExec) ReplaceLogger(logger logr.Logger) {
+	p.logger = logger
+}
+
+func (p *OutputPluginExec) Cleanup() error {
+	return nil
+}
+
+func (p *OutputPluginExec) metricLabels() (string, string) {
+	return "exec", p.config.Command
+}
+
+func (p *OutputPluginExec) Load(
+	ctx context.Context,
+	messages chan []GallonRecord,
+	errs chan error,
+) error {
+	cmd := newExecCommand(ctx, p.logger, p.config)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start command: %v (error: %v)", p.config.Command, err)
+	}
+
+	// the messages from the command are read until it closes stdout
+	readDone := make(chan error, 1)
+	go func() {
+		decoder := json.NewDecoder(stdout)
+		for {
+			var msg execMessage
+			if err := decoder.Decode(&msg); err != nil {
+				if errors.Is(err, io.EOF) {
+					readDone <- nil
+				} else {
+					readDone <- fmt.Errorf("invalid message from command: %v", err)
+				}
+				return
+			}
+
+			switch msg.Type {
+			case "error":
+				errs <- newExecError(msg)
+			default:
+				readDone <- fmt.Errorf("unknown message type from command: %v", msg.Type)
+				return
+			}
+		}
+	}()
+
+	loadErr := p.write(ctx, stdin, messages)
+	if err := stdin.Close(); err != nil && loadErr == nil {
+		loadErr = err
+	}
+
+	readErr := <-readDone
+	if readErr != nil {
+		// the command must exit even if it keeps writing
+		_ = cmd.Cancel()
+	}
+
+	waitErr := cmd.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if readErr != nil {
+		return readErr
+	}
+	if waitErr != nil {
+		return fmt.Errorf("command failed: %v (error: %v)", p.config.Command, waitErr)
+	}
+
+	return loadErr
+}
+
+// write sends the init message and the records to stdin of the command.
+func (p *OutputPluginExec) write(ctx context.Context, stdin io.Writer, messages chan []GallonRecord) error {
+	encoder := json.NewEncoder(stdin)
+	if err := encoder.Encode(execMessage{Type: "init", Config: p.config.Config}); err != nil {
+		return fmt.Errorf("failed to send init message: %v", err)
+	}
+	if p.schema != nil {
+		if err := encoder.Encode(execMessage{Type: "schema", Columns: execColumns(*p.schema)}); err != nil {
+			return fmt.Errorf("failed to send schema message: %v", err)
+		}
+	}
+
+	loadedTotal := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msgs, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			batchStartedAt := time.Now()
+			_, batchSpan := startLoadBatchSpan(ctx, p, len(msgs))
+
+			records := make([]*GallonRecord, len(msgs))
+			for i := range msgs {
+				records[i] = &msgs[i]
+			}
+
+			if err := encoder.Encode(execMessage{Type: "records", Records: records}); err != nil {
+				err = fmt.Errorf("failed to send records: %v", err)
+				endSpan(batchSpan, err)
+				return err
+			}
+
+			endSpan(batchSpan, nil)
+			observeBatchDuration(GallonStageLoad, p, batchStartedAt)
+			addLoadedRecords(ctx, p, len(msgs))
+
+			loadedTotal += len(msgs)
+			p.logger.Info(fmt.Sprintf("loaded %v records", loadedTotal))
+		}
+	}
+}
+
+func NewOutputPluginExecFromConfig(configYml []byte) (*OutputPluginExec, error) {
+	var outConfig GallonConfig[any, ExecConfig]
+	if err := yaml.Unmarshal(configYml, &outConfig); err != nil {
+		return nil, err
+	}
+
+	if outConfig.Out.Command == "" {
+		return nil, errors.New("command is required for exec plugin")
+	}
+
+	return NewOutputPluginExec(outConfig.Out), nil
+}
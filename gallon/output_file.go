@@ -1,15 +1,18 @@
 package gallon
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"gopkg.in/yaml.v3"
@@ -19,6 +22,15 @@ type OutputPluginFile struct {
 	logger      logr.Logger
 	deserialize func(GallonRecord) ([]byte, error)
 	newWriter   func() (io.WriteCloser, error)
+	ack         func()
+	// filepath and format are set by NewOutputPluginFileFromConfig to count the records in the file
+	filepath string
+	format   string
+	// header writes the names of the columns before the records (csv only)
+	header bool
+	append bool
+	// schema is set by SetSchema
+	schema *GallonSchema
 }
 
 func NewOutputPluginFile(
@@ -32,6 +44,26 @@ func NewOutputPluginFile(
 }
 
 var _ OutputPlugin = &OutputPluginFile{}
+var _ ValidatingOutputPlugin = &OutputPluginFile{}
+var _ AckOutputPlugin = &OutputPluginFile{}
+var _ CountingOutputPlugin = &OutputPluginFile{}
+var _ SchemaOutputPlugin = &OutputPluginFile{}
+
+// SetSchema makes the columns follow the schema: the values are serialized by their types,
+// and missing columns are written as null (jsonl) or empty cells (csv).
+func (p *OutputPluginFile) SetSchema(schema GallonSchema) {
+	p.schema = &schema
+
+	if p.format != "" {
+		if deserializer, err := defineSchemaDeserializer(p.format, schema); err == nil {
+			p.deserialize = deserializer
+		}
+	}
+}
+
+func (p *OutputPluginFile) SetAckHandler(ack func()) {
+	p.ack = ack
+}
 
 func (p *OutputPluginFile) ReplaceLogger(logger logr.Logger) {
 	p.logger = logger
@@ -41,11 +73,81 @@ func (p *OutputPluginFile) Cleanup() error {
 	return nil
 }
 
+func (p *OutputPluginFile) metricLabels() (string, string) {
+	return "file", ""
+}
+
+func (p *OutputPluginFile) Validate(record GallonRecord) error {
+	if _, err := p.deserialize(record); err != nil {
+		return fmt.Errorf("failed to deserialize: %v (error: %v)", record, err)
+	}
+
+	return nil
+}
+
+// Count reads the file and counts the records in it. Note that the records written before the migration are also counted in append mode.
+func (p *OutputPluginFile) Count(ctx context.Context) (int, error) {
+	if p.filepath == "" {
+		return 0, errors.New("filepath is unknown to count the records")
+	}
+
+	fs, err := os.Open(p.filepath)
+	if err != nil {
+		return 0, err
+	}
+	defer fs.Close()
+
+	switch strings.ToLower(p.format) {
+	case "csv":
+		reader := csv.NewReader(fs)
+		reader.FieldsPerRecord = -1
+
+		count := 0
+		for {
+			if _, err := reader.Read(); err != nil {
+				if errors.Is(err, io.EOF) {
+					if p.header && count > 0 {
+						count--
+					}
+
+					return count, nil
+				}
+
+				return 0, fmt.Errorf("failed to read csv file: %v (error: %v)", p.filepath, err)
+			}
+			count++
+		}
+	default:
+		scanner := bufio.NewScanner(fs)
+		scanner.Buffer(nil, 64*1024*1024)
+
+		count := 0
+		for scanner.Scan() {
+			if len(bytes.TrimSpace(scanner.Bytes())) > 0 {
+				count++
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return 0, fmt.Errorf("failed to read file: %v (error: %v)", p.filepath, err)
+		}
+
+		return count, nil
+	}
+}
+
 func (p *OutputPluginFile) Load(
 	ctx context.Context,
 	messages chan []GallonRecord,
 	errs chan error,
 ) error {
+	// the header is written only once to the file
+	header := p.header
+	if header && p.append && p.filepath != "" {
+		if info, err := os.Stat(p.filepath); err == nil && info.Size() > 0 {
+			header = false
+		}
+	}
+
 	fs, err := p.newWriter()
 	if err != nil {
 		return err
@@ -69,32 +171,77 @@ loop:
 				break loop
 			}
 
+			batchStartedAt := time.Now()
+			_, batchSpan := startLoadBatchSpan(ctx, p, len(msgs))
+			written := 0
+
+			if header && len(msgs) > 0 {
+				if err := p.writeHeader(fs, msgs[0]); err != nil {
+					endSpan(batchSpan, err)
+					return err
+				}
+				header = false
+			}
+
 			for _, msg := range msgs {
 				bs, err := p.deserialize(msg)
 				if err != nil {
-					errs <- errors.New("failed to deserialize record: " + fmt.Sprintf("%v", msg))
+					errs <- NewRecordError(&msg, errors.New("failed to deserialize record: "+fmt.Sprintf("%v", msg)))
 					continue
 				}
 
 				if _, err := fs.Write(bs); err != nil {
+					endSpan(batchSpan, err)
 					return err
 				}
+				written++
 			}
 
+			endSpan(batchSpan, nil)
+			observeBatchDuration(GallonStageLoad, p, batchStartedAt)
+			addLoadedRecords(ctx, p, written)
+
 			if len(msgs) > 0 {
 				loadedTotal += len(msgs)
 				p.logger.Info(fmt.Sprintf("loaded %v records", loadedTotal))
 			}
+
+			if p.ack != nil {
+				if syncer, ok := fs.(interface{ Sync() error }); ok {
+					if err := syncer.Sync(); err != nil {
+						return err
+					}
+				}
+
+				p.ack()
+			}
 		}
 	}
 
 	return nil
 }
 
+// writeHeader writes the names of the columns in the schema, or the keys of the first record if the schema is unknown.
+func (p *OutputPluginFile) writeHeader(w io.Writer, first GallonRecord) error {
+	names := first.Keys()
+	if p.schema != nil {
+		names = p.schema.Names()
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(names); err != nil {
+		return err
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
 type OutputPluginFileConfig struct {
 	Filepath string `yaml:"filepath"`
 	Format   string `yaml:"format"`
 	Header   *bool  `yaml:"header"`
+	Append   bool   `yaml:"append"`
 }
 
 func NewOutputPluginFileFromConfig(configYml []byte) (*OutputPluginFile, error) {
@@ -110,10 +257,15 @@ func NewOutputPluginFileFromConfig(configYml []byte) (*OutputPluginFile, error)
 		return nil, err
 	}
 
-	return NewOutputPluginFile(
+	plugin := NewOutputPluginFile(
 		deserializer,
 		func() (io.WriteCloser, error) {
-			fs, err := os.Create(config.Filepath)
+			flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			if config.Append {
+				flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+			}
+
+			fs, err := os.OpenFile(config.Filepath, flag, 0666)
 			if err != nil {
 				return nil, err
 			}
@@ -122,7 +274,13 @@ func NewOutputPluginFileFromConfig(configYml []byte) (*OutputPluginFile, error)
 
 			return fs, nil
 		},
-	), nil
+	)
+	plugin.filepath = config.Filepath
+	plugin.format = config.Format
+	plugin.header = config.Header != nil && *config.Header && strings.ToLower(config.Format) == "csv"
+	plugin.append = config.Append
+
+	return plugin, nil
 }
 
 func defineDeserializer(format string) (func(GallonRecord) ([]byte, error), error) {
@@ -160,3 +318,85 @@ func defineDeserializer(format string) (func(GallonRecord) ([]byte, error), erro
 		return nil, errors.New("unknown format: " + format)
 	}
 }
+
+// defineSchemaDeserializer defines the deserializer whose columns follow the schema.
+// In jsonl, the columns not in the schema are written after them. In csv, only the columns in the schema are written, to match the header.
+func defineSchemaDeserializer(format string, schema GallonSchema) (func(GallonRecord) ([]byte, error), error) {
+	switch strings.ToLower(format) {
+	case "jsonl":
+		return func(i GallonRecord) ([]byte, error) {
+			record := NewGallonRecord()
+			for _, field := range schema.Fields {
+				value, _ := i.Get(field.Name)
+				record.Set(field.Name, value)
+			}
+			for _, k := range i.Keys() {
+				if _, ok := schema.Field(k); !ok {
+					value, _ := i.Get(k)
+					record.Set(k, value)
+				}
+			}
+
+			j, err := record.MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+
+			return []byte(fmt.Sprintf("%v\n", string(j))), nil
+		}, nil
+	case "csv":
+		return func(i GallonRecord) ([]byte, error) {
+			cells := []string{}
+			for _, field := range schema.Fields {
+				value, _ := i.Get(field.Name)
+
+				cell, err := csvCell(field.Type, value)
+				if err != nil {
+					return nil, fmt.Errorf("failed to serialize column: %v (error: %v)", field.Name, err)
+				}
+
+				cells = append(cells, cell)
+			}
+
+			buf := new(bytes.Buffer)
+			writer := csv.NewWriter(buf)
+			if err := writer.WriteAll([][]string{cells}); err != nil {
+				return nil, err
+			}
+
+			return buf.Bytes(), nil
+		}, nil
+	default:
+		return nil, errors.New("unknown format: " + format)
+	}
+}
+
+// csvCell serializes a value by the type. nil is an empty cell, and nested values are serialized as JSON.
+func csvCell(t GallonType, value any) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+
+	switch t {
+	case GallonTypeTime:
+		v, ok := value.(time.Time)
+		if !ok {
+			return "", fmt.Errorf("value is not time: %v", value)
+		}
+
+		return v.Format(time.RFC3339Nano), nil
+	case GallonTypeJSON, GallonTypeObject, GallonTypeArray, GallonTypeAny:
+		if v, ok := value.(string); ok && t != GallonTypeJSON {
+			return v, nil
+		}
+
+		j, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+
+		return string(j), nil
+	default:
+		return fmt.Sprintf("%v", value), nil
+	}
+}
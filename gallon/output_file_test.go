@@ -76,7 +76,8 @@ out:
 		t.Errorf("Could not run command: %s", err)
 	}
 
-	expected := `1,foo,20,1234567890
+	expected := `id,name,age,created_at
+1,foo,20,1234567890
 2,bar,30,1234567890
 3,baz,40,1234567890
 `
@@ -0,0 +1,190 @@
+package gallon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-logr/logr"
+)
+
+// previewCellWidth is the maximum width of a cell in the table. Longer values are truncated.
+const previewCellWidth = 40
+
+// OutputPluginPreview keeps the records in memory to render them as a table or JSON lines, instead of loading them anywhere.
+type OutputPluginPreview struct {
+	logger logr.Logger
+
+	mu      sync.Mutex
+	records []GallonRecord
+	schema  *GallonSchema
+}
+
+func NewOutputPluginPreview() *OutputPluginPreview {
+	return &OutputPluginPreview{}
+}
+
+var _ OutputPlugin = &OutputPluginPreview{}
+var _ SchemaOutputPlugin = &OutputPluginPreview{}
+
+func (p *OutputPluginPreview) ReplaceLogger(logger logr.Logger) {
+	p.logger = logger
+}
+
+func (p *OutputPluginPreview) Cleanup() error {
+	return nil
+}
+
+func (p *OutputPluginPreview) metricLabels() (string, string) {
+	return "preview", ""
+}
+
+func (p *OutputPluginPreview) SetSchema(schema GallonSchema) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.schema = &schema
+}
+
+func (p *OutputPluginPreview) Load(
+	ctx context.Context,
+	messages chan []GallonRecord,
+	errs chan error,
+) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msgs, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			p.mu.Lock()
+			p.records = append(p.records, msgs...)
+			p.mu.Unlock()
+		}
+	}
+}
+
+// Records returns the loaded records.
+func (p *OutputPluginPreview) Records() []GallonRecord {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return slices.Clone(p.records)
+}
+
+// Columns returns the names of the fields in the schema if it is known, followed by the other keys of the records in the order of appearance.
+func (p *OutputPluginPreview) Columns() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	columns := []string{}
+	if p.schema != nil {
+		columns = append(columns, p.schema.Names()...)
+	}
+	for _, record := range p.records {
+		for _, key := range record.Keys() {
+			if !slices.Contains(columns, key) {
+				columns = append(columns, key)
+			}
+		}
+	}
+
+	return columns
+}
+
+// RenderTable writes the records as a table aligned by the columns. NULL (or a missing key) is written as `NULL`.
+func (p *OutputPluginPreview) RenderTable(w io.Writer) error {
+	columns := p.Columns()
+	records := p.Records()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	separators := []string{}
+	for _, column := range columns {
+		separators = append(separators, strings.Repeat("-", min(utf8.RuneCountInString(column), previewCellWidth)))
+	}
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	fmt.Fprintln(tw, strings.Join(separators, "\t"))
+
+	for _, record := range records {
+		cells := []string{}
+		for _, column := range columns {
+			value, _ := record.Get(column)
+
+			cell, err := previewCell(value)
+			if err != nil {
+				return fmt.Errorf("failed to render column: %v (error: %v)", column, err)
+			}
+
+			cells = append(cells, cell)
+		}
+
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "(%v records)\n", len(records))
+	return err
+}
+
+// RenderJSON writes the records as JSON lines.
+func (p *OutputPluginPreview) RenderJSON(w io.Writer) error {
+	for _, record := range p.Records() {
+		j, err := record.MarshalJSON()
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, "%v\n", string(j)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// previewCell formats a value in a line of the table.
+func previewCell(value any) (string, error) {
+	var cell string
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		cell = v
+	case []byte:
+		cell = string(v)
+	case time.Time:
+		cell = v.Format(time.RFC3339Nano)
+	case bool, int, int32, int64, float32, float64:
+		cell = fmt.Sprintf("%v", v)
+	default:
+		j, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+
+		cell = string(j)
+	}
+
+	// the tabs and the newlines break the alignment
+	cell = strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`).Replace(cell)
+
+	if utf8.RuneCountInString(cell) > previewCellWidth {
+		cell = string([]rune(cell)[:previewCellWidth-3]) + "..."
+	}
+
+	return cell, nil
+}
@@ -0,0 +1,88 @@
+package gallon
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_preview(t *testing.T) {
+	r1 := NewGallonRecord()
+	r1.Set("id", int64(1))
+	r1.Set("name", "foo")
+	r1.Set("created_at", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	r1.Set("address", map[string]any{"city": "Tokyo"})
+
+	r2 := NewGallonRecord()
+	r2.Set("id", int64(2))
+	r2.Set("name", "a long name\twith a tab, which is truncated in the table")
+	r2.Set("memo", "extra")
+
+	r3 := NewGallonRecord()
+	r3.Set("id", int64(3))
+
+	tests := []struct {
+		name     string
+		input    InputPlugin
+		json     bool
+		expected string
+	}{
+		{
+			name:  "table",
+			input: NewInputPluginLimit(NewInputPluginStub([][]GallonRecord{{r1, r2}, {r3}}), 2),
+			expected: `id  name                                      created_at            address           memo
+--  ----                                      ----------            -------           ----
+1   foo                                       2024-01-02T03:04:05Z  {"city":"Tokyo"}  NULL
+2   a long name\twith a tab, which is tru...  NULL                  NULL              extra
+(2 records)
+`,
+		},
+		{
+			name: "table with schema",
+			input: &schemaInputPluginStub{
+				InputPluginStub: NewInputPluginStub([][]GallonRecord{{r3}}),
+				schema: GallonSchema{Fields: []GallonField{
+					{Name: "id", Type: GallonTypeInt},
+					{Name: "name", Type: GallonTypeString, Nullable: true},
+				}},
+			},
+			expected: `id  name
+--  ----
+3   NULL
+(1 records)
+`,
+		},
+		{
+			name:  "json",
+			input: NewInputPluginStub([][]GallonRecord{{r1}, {r3}}),
+			json:  true,
+			expected: `{"id":1,"name":"foo","created_at":"2024-01-02T03:04:05Z","address":{"city":"Tokyo"}}
+{"id":3}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := NewOutputPluginPreview()
+
+			g := Gallon{
+				Logger: logger,
+				Input:  tt.input,
+				Output: output,
+			}
+			assert.NoError(t, g.Run(context.Background()))
+
+			buf := new(bytes.Buffer)
+			if tt.json {
+				assert.NoError(t, output.RenderJSON(buf))
+			} else {
+				assert.NoError(t, output.RenderTable(buf))
+			}
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
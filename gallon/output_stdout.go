@@ -23,6 +23,7 @@ func NewOutputPluginStdout(
 }
 
 var _ OutputPlugin = &OutputPluginStdout{}
+var _ ValidatingOutputPlugin = &OutputPluginStdout{}
 
 func (p *OutputPluginStdout) ReplaceLogger(logger logr.Logger) {
 	p.logger = logger
@@ -32,6 +33,18 @@ func (p *OutputPluginStdout) Cleanup() error {
 	return nil
 }
 
+func (p *OutputPluginStdout) metricLabels() (string, string) {
+	return "stdout", ""
+}
+
+func (p *OutputPluginStdout) Validate(record GallonRecord) error {
+	if _, err := p.deserialize(record); err != nil {
+		return fmt.Errorf("failed to deserialize: %v (error: %v)", record, err)
+	}
+
+	return nil
+}
+
 func (p *OutputPluginStdout) Load(
 	ctx context.Context,
 	messages chan []GallonRecord,
@@ -50,16 +63,22 @@ loop:
 				break loop
 			}
 
+			_, batchSpan := startLoadBatchSpan(ctx, p, len(msgs))
+			written := 0
 			for _, msg := range msgs {
 				bs, err := p.deserialize(msg)
 				if err != nil {
-					errs <- fmt.Errorf("failed to deserialize message: %v (error: %v)", msg, err)
+					errs <- NewRecordError(&msg, fmt.Errorf("failed to deserialize message: %v (error: %v)", msg, err))
 					continue
 				}
 
 				p.logger.Info(string(bs))
+				written++
 			}
 
+			endSpan(batchSpan, nil)
+			addLoadedRecords(ctx, p, written)
+
 			if len(msgs) > 0 {
 				loadedTotal += len(msgs)
 				p.logger.Info(fmt.Sprintf("loaded %v records", loadedTotal))
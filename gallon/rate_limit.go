@@ -0,0 +1,78 @@
+package gallon
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimit limits the throughput of a migration, regardless of the input plugin and its page size.
+// The batches are held between the input and the output plugins, so the input plugin is slowed down as well.
+// Zero (or unset) means no limit.
+type RateLimit struct {
+	// RecordsPerSecond is the maximum number of records per second.
+	RecordsPerSecond float64 `yaml:"recordsPerSecond"`
+	// BatchesPerSecond is the maximum number of batches (pages of the input plugin) per second.
+	BatchesPerSecond float64 `yaml:"batchesPerSecond"`
+}
+
+// Validate returns an error if the limits are negative.
+func (l RateLimit) Validate() error {
+	if l.RecordsPerSecond < 0 || l.BatchesPerSecond < 0 {
+		return errors.New("rateLimit must not be negative")
+	}
+
+	return nil
+}
+
+// rateLimiter enforces RateLimit. A nil rateLimiter does not limit anything.
+type rateLimiter struct {
+	records *rate.Limiter
+	batches *rate.Limiter
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit.RecordsPerSecond <= 0 && limit.BatchesPerSecond <= 0 {
+		return nil
+	}
+
+	l := &rateLimiter{}
+	if limit.RecordsPerSecond > 0 {
+		// a second worth of records can be sent at once
+		l.records = rate.NewLimiter(rate.Limit(limit.RecordsPerSecond), int(math.Max(1, math.Ceil(limit.RecordsPerSecond))))
+	}
+	if limit.BatchesPerSecond > 0 {
+		l.batches = rate.NewLimiter(rate.Limit(limit.BatchesPerSecond), 1)
+	}
+
+	return l
+}
+
+// wait blocks until a batch of the records is allowed, or the context is cancelled.
+func (l *rateLimiter) wait(ctx context.Context, records int) error {
+	if l == nil {
+		return nil
+	}
+
+	if l.batches != nil {
+		if err := l.batches.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	if l.records != nil {
+		// a batch larger than the burst is waited for in chunks
+		for records > 0 {
+			n := min(records, l.records.Burst())
+			if err := l.records.WaitN(ctx, n); err != nil {
+				return err
+			}
+
+			records -= n
+		}
+	}
+
+	return nil
+}
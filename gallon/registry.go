@@ -0,0 +1,176 @@
+package gallon
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// InputPluginFactory creates an input plugin from the whole config yaml (the plugin reads its config from `in`).
+type InputPluginFactory func(configYml []byte) (InputPlugin, error)
+
+// OutputPluginFactory creates an output plugin from the whole config yaml (the plugin reads its config from `out`).
+type OutputPluginFactory func(configYml []byte) (OutputPlugin, error)
+
+// TransformPluginFactory creates a transform plugin from an element of `transforms`.
+type TransformPluginFactory func(configYml []byte) (TransformPlugin, error)
+
+var (
+	registryMu       sync.RWMutex
+	inputPlugins     = map[string]InputPluginFactory{}
+	outputPlugins    = map[string]OutputPluginFactory{}
+	transformPlugins = map[string]TransformPluginFactory{}
+)
+
+func init() {
+	RegisterInputPlugin("dynamodb", func(configYml []byte) (InputPlugin, error) {
+		return NewInputPluginDynamoDbFromConfig(configYml)
+	})
+	RegisterInputPlugin("sql", func(configYml []byte) (InputPlugin, error) {
+		return NewInputPluginSqlFromConfig(configYml)
+	})
+	RegisterInputPlugin("random", func(configYml []byte) (InputPlugin, error) {
+		return NewInputPluginRandomFromConfig(configYml)
+	})
+	RegisterInputPlugin("exec", func(configYml []byte) (InputPlugin, error) {
+		return NewInputPluginExecFromConfig(configYml)
+	})
+
+	RegisterOutputPlugin("bigquery", func(configYml []byte) (OutputPlugin, error) {
+		return NewOutputPluginBigQueryFromConfig(configYml)
+	})
+	RegisterOutputPlugin("file", func(configYml []byte) (OutputPlugin, error) {
+		return NewOutputPluginFileFromConfig(configYml)
+	})
+	RegisterOutputPlugin("stdout", func(configYml []byte) (OutputPlugin, error) {
+		return NewOutputPluginStdoutFromConfig(configYml)
+	})
+	RegisterOutputPlugin("exec", func(configYml []byte) (OutputPlugin, error) {
+		return NewOutputPluginExecFromConfig(configYml)
+	})
+
+	RegisterTransformPlugin("rename", func(configYml []byte) (TransformPlugin, error) {
+		return NewTransformPluginRenameFromConfig(configYml)
+	})
+	RegisterTransformPlugin("cast", func(configYml []byte) (TransformPlugin, error) {
+		return NewTransformPluginCastFromConfig(configYml)
+	})
+	RegisterTransformPlugin("drop", func(configYml []byte) (TransformPlugin, error) {
+		return NewTransformPluginDropFromConfig(configYml)
+	})
+	RegisterTransformPlugin("compute", func(configYml []byte) (TransformPlugin, error) {
+		return NewTransformPluginComputeFromConfig(configYml)
+	})
+}
+
+// RegisterInputPlugin makes the input plugin available as `type: <t>` in the config.
+// It panics if the type is already registered or the factory is nil, so it is usually called in an init function.
+func RegisterInputPlugin(t string, factory InputPluginFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("gallon: RegisterInputPlugin factory is nil")
+	}
+	if _, ok := inputPlugins[t]; ok {
+		panic("gallon: RegisterInputPlugin called twice for type " + t)
+	}
+
+	inputPlugins[t] = factory
+}
+
+// RegisterOutputPlugin makes the output plugin available as `type: <t>` in the config.
+// It panics if the type is already registered or the factory is nil, so it is usually called in an init function.
+func RegisterOutputPlugin(t string, factory OutputPluginFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("gallon: RegisterOutputPlugin factory is nil")
+	}
+	if _, ok := outputPlugins[t]; ok {
+		panic("gallon: RegisterOutputPlugin called twice for type " + t)
+	}
+
+	outputPlugins[t] = factory
+}
+
+// RegisterTransformPlugin makes the transform plugin available as `type: <t>` in `transforms`.
+// It panics if the type is already registered or the factory is nil, so it is usually called in an init function.
+func RegisterTransformPlugin(t string, factory TransformPluginFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("gallon: RegisterTransformPlugin factory is nil")
+	}
+	if _, ok := transformPlugins[t]; ok {
+		panic("gallon: RegisterTransformPlugin called twice for type " + t)
+	}
+
+	transformPlugins[t] = factory
+}
+
+// NewInputPluginFromConfig creates the input plugin registered as the type.
+func NewInputPluginFromConfig(t string, configYml []byte) (InputPlugin, error) {
+	registryMu.RLock()
+	factory, ok := inputPlugins[t]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("plugin not found: %v", t)
+	}
+
+	return factory(configYml)
+}
+
+// NewOutputPluginFromConfig creates the output plugin registered as the type.
+func NewOutputPluginFromConfig(t string, configYml []byte) (OutputPlugin, error) {
+	registryMu.RLock()
+	factory, ok := outputPlugins[t]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("plugin not found: %v", t)
+	}
+
+	return factory(configYml)
+}
+
+// NewTransformPluginFromConfig creates the transform plugin registered as the type.
+func NewTransformPluginFromConfig(t string, configYml []byte) (TransformPlugin, error) {
+	registryMu.RLock()
+	factory, ok := transformPlugins[t]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("plugin not found: %v", t)
+	}
+
+	return factory(configYml)
+}
+
+// InputPlugins returns the registered types of the input plugins in sorted order.
+func InputPlugins() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return slices.Sorted(maps.Keys(inputPlugins))
+}
+
+// OutputPlugins returns the registered types of the output plugins in sorted order.
+func OutputPlugins() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return slices.Sorted(maps.Keys(outputPlugins))
+}
+
+// TransformPlugins returns the registered types of the transform plugins in sorted order.
+func TransformPlugins() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return slices.Sorted(maps.Keys(transformPlugins))
+}
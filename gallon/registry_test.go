@@ -0,0 +1,56 @@
+package gallon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var registryTestConfig []byte
+
+// registerTestInputPlugin registers `test_registry` input plugin, which is removed when the test finishes
+// since the registry is global and the plugin should not be listed in the other tests.
+func registerTestInputPlugin(t *testing.T) {
+	RegisterInputPlugin("test_registry", func(configYml []byte) (InputPlugin, error) {
+		registryTestConfig = configYml
+		return NewInputPluginStub(nil), nil
+	})
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+
+		delete(inputPlugins, "test_registry")
+	})
+}
+
+func Test_registry(t *testing.T) {
+	assert.Subset(t, InputPlugins(), []string{"dynamodb", "random", "sql"})
+	assert.Subset(t, OutputPlugins(), []string{"bigquery", "file", "stdout"})
+	assert.Subset(t, TransformPlugins(), []string{"cast", "compute", "drop", "rename"})
+
+	registerTestInputPlugin(t)
+
+	input, err := NewInputPluginFromConfig("test_registry", []byte("in:\n  type: test_registry\n"))
+	assert.NoError(t, err)
+	assert.IsType(t, &InputPluginStub{}, input)
+	assert.Equal(t, "in:\n  type: test_registry\n", string(registryTestConfig))
+	assert.Contains(t, InputPlugins(), "test_registry")
+
+	assert.Panics(t, func() {
+		RegisterInputPlugin("test_registry", func(configYml []byte) (InputPlugin, error) {
+			return nil, nil
+		})
+	})
+
+	_, err = NewOutputPluginFromConfig("unknown", nil)
+	assert.EqualError(t, err, "plugin not found: unknown")
+}
+
+func Test_registry_cleanup(t *testing.T) {
+	t.Run("register", func(t *testing.T) {
+		registerTestInputPlugin(t)
+		assert.Contains(t, InputPlugins(), "test_registry")
+	})
+
+	assert.NotContains(t, InputPlugins(), "test_registry")
+}
@@ -0,0 +1,131 @@
+package gallon
+
+import (
+	"slices"
+)
+
+// GallonType is the logical type of the values of a field in GallonRecord.
+type GallonType string
+
+const (
+	GallonTypeString GallonType = "string"
+	// GallonTypeInt is int64
+	GallonTypeInt GallonType = "int"
+	// GallonTypeFloat is float64
+	GallonTypeFloat GallonType = "float"
+	GallonTypeBool  GallonType = "bool"
+	// GallonTypeTime is time.Time
+	GallonTypeTime GallonType = "time"
+	// GallonTypeDate is a string in the format of `2006-01-02`
+	GallonTypeDate GallonType = "date"
+	// GallonTypeNumber is a decimal number in a string, e.g. a DynamoDB number
+	GallonTypeNumber GallonType = "number"
+	// GallonTypeJSON is a value parsed from JSON (map[string]any, []any, string, float64, bool or nil)
+	GallonTypeJSON GallonType = "json"
+	// GallonTypeObject is map[string]any, whose fields are GallonField.Fields
+	GallonTypeObject GallonType = "object"
+	// GallonTypeArray is []any, whose items are GallonField.Items
+	GallonTypeArray GallonType = "array"
+	// GallonTypeAny is a value of an unknown type
+	GallonTypeAny GallonType = "any"
+)
+
+// GallonField is a field of GallonSchema.
+type GallonField struct {
+	Name     string
+	Type     GallonType
+	Nullable bool
+	// Fields are the fields of an object, if known.
+	Fields []GallonField
+	// Items is the field of the items of an array, if known. Its name is empty.
+	Items *GallonField
+
+	// source describes where the type comes from for messages, e.g. `dynamodb number`
+	source string
+}
+
+// GallonSchema describes the fields of the records, in the order of the fields in the records.
+// The input plugins which know the schema before extracting (See SchemaInputPlugin) expose it,
+// and the schema is passed to the outputs (See SchemaOutputPlugin) through the transforms (See SchemaTransformPlugin).
+type GallonSchema struct {
+	Fields []GallonField
+}
+
+// Field returns the field with the name.
+func (s GallonSchema) Field(name string) (GallonField, bool) {
+	i := slices.IndexFunc(s.Fields, func(f GallonField) bool { return f.Name == name })
+	if i < 0 {
+		return GallonField{}, false
+	}
+
+	return s.Fields[i], true
+}
+
+// Names returns the names of the fields.
+func (s GallonSchema) Names() []string {
+	names := []string{}
+	for _, f := range s.Fields {
+		names = append(names, f.Name)
+	}
+
+	return names
+}
+
+// SchemaInputPlugin is an InputPlugin which knows the schema of the records before extracting.
+type SchemaInputPlugin interface {
+	InputPlugin
+
+	// Schema returns the schema of the extracted records, or nil if it is unknown (e.g. the raw query mode of sql).
+	Schema() *GallonSchema
+}
+
+// SchemaTransformPlugin is a TransformPlugin which knows how the schema changes.
+// If a transform does not implement it, the schema after the transform is unknown.
+type SchemaTransformPlugin interface {
+	TransformPlugin
+
+	// TransformSchema returns the schema of the transformed records.
+	TransformSchema(schema GallonSchema) GallonSchema
+}
+
+// SchemaOutputPlugin is an OutputPlugin which uses the schema of the records, e.g. for typed serialization.
+type SchemaOutputPlugin interface {
+	OutputPlugin
+
+	// SetSchema is called before Load if the schema is known.
+	SetSchema(schema GallonSchema)
+}
+
+// schema returns the schema of the records sent to the outputs, or nil if it is unknown.
+func (g *Gallon) schema() *GallonSchema {
+	input, ok := g.Input.(SchemaInputPlugin)
+	if !ok {
+		return nil
+	}
+
+	schema := input.Schema()
+	if schema == nil {
+		return nil
+	}
+
+	result := *schema
+	for _, transform := range g.Transforms {
+		t, ok := transform.(SchemaTransformPlugin)
+		if !ok {
+			return nil
+		}
+
+		result = t.TransformSchema(result)
+	}
+
+	return &result
+}
+
+// description describes the type of the field for messages.
+func (f GallonField) description() string {
+	if f.source != "" {
+		return f.source
+	}
+
+	return string(f.Type)
+}
@@ -0,0 +1,280 @@
+package gallon
+
+import (
+	"fmt"
+	"slices"
+
+	"cloud.google.com/go/bigquery"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaIssueKind is the kind of incompatibility between the input and the output schemas.
+type SchemaIssueKind string
+
+const (
+	// SchemaIssueMissingColumn is a column of the input which is not in the output schema. Its values are not loaded.
+	SchemaIssueMissingColumn SchemaIssueKind = "missing"
+	// SchemaIssueNullColumn is a column of the output schema which is not in the input. It is always NULL.
+	SchemaIssueNullColumn SchemaIssueKind = "null"
+	// SchemaIssueTypeMismatch is a column whose values cannot be loaded as the type of the output schema.
+	SchemaIssueTypeMismatch SchemaIssueKind = "typeMismatch"
+)
+
+// SchemaIssue is an incompatibility found by CheckSchemaCompatibility.
+type SchemaIssue struct {
+	// Output is the section of the output plugin, e.g. `out` or `outs[1]`.
+	Output string
+	// Column is the name of the column in the records. Nested columns are joined with dots.
+	Column  string
+	Kind    SchemaIssueKind
+	Message string
+}
+
+func (i SchemaIssue) String() string {
+	return fmt.Sprintf("%v: %v: %v", i.Output, i.Column, i.Message)
+}
+
+// CheckSchemaCompatibility compares the schema of the input plugin in the config with the schemas of the output plugins,
+// applying renames of the input schema and the transforms. No connection to the source or the destination is made.
+//
+// The input must be `sql` (except the raw query mode) or `dynamodb`, and the outputs must be `bigquery`.
+// Other plugins have no static schema to compare, and are skipped.
+func CheckSchemaCompatibility(configYml []byte) ([]SchemaIssue, error) {
+	var config GallonConfig[yaml.Node, yaml.Node]
+	if err := yaml.Unmarshal(configYml, &config); err != nil {
+		return nil, err
+	}
+
+	schema, err := transformedSchema(&config)
+	if err != nil || schema == nil {
+		return nil, err
+	}
+
+	outputs := map[string]*yaml.Node{}
+	names := []string{}
+	if !config.Out.IsZero() {
+		outputs["out"] = &config.Out
+		names = append(names, "out")
+	}
+	for i := range config.Outs {
+		name := fmt.Sprintf("outs[%v]", i)
+		outputs[name] = &config.Outs[i]
+		names = append(names, name)
+	}
+
+	issues := []SchemaIssue{}
+	for _, name := range names {
+		var withType struct {
+			Type string `yaml:"type"`
+		}
+		if err := outputs[name].Decode(&withType); err != nil {
+			return nil, err
+		}
+		if withType.Type != "bigquery" {
+			continue
+		}
+
+		var out OutputPluginBigQueryConfig
+		if err := outputs[name].Decode(&out); err != nil {
+			return nil, err
+		}
+
+		// the schema inferred from the input is always compatible
+		if out.Schema.Len() == 0 {
+			continue
+		}
+
+		outSchema, err := getSchemaFromConfig(out.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema in %v: %v", name, err)
+		}
+
+		for _, issue := range compareBigQuerySchema("", schema.Fields, outSchema) {
+			issue.Output = name
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues, nil
+}
+
+// InputSchema returns the schema of the input plugin in the config without connecting to the source,
+// or nil if it is unknown (See SchemaInputPlugin).
+func InputSchema(configYml []byte) (*GallonSchema, error) {
+	var config GallonConfig[yaml.Node, any]
+	if err := yaml.Unmarshal(configYml, &config); err != nil {
+		return nil, err
+	}
+
+	return inputSchema(&config.In)
+}
+
+func inputSchema(node *yaml.Node) (*GallonSchema, error) {
+	var withType struct {
+		Type string `yaml:"type"`
+	}
+	if err := node.Decode(&withType); err != nil {
+		return nil, err
+	}
+
+	switch withType.Type {
+	case "sql":
+		var config InputPluginSqlConfig
+		if err := node.Decode(&config); err != nil {
+			return nil, err
+		}
+
+		return config.gallonSchema(), nil
+	case "dynamodb":
+		var config InputPluginDynamoDbConfig
+		if err := node.Decode(&config); err != nil {
+			return nil, err
+		}
+
+		return config.gallonSchema(), nil
+	default:
+		return nil, nil
+	}
+}
+
+// TransformedSchema returns the schema of the records sent to the outputs, which is the schema of the input plugin (See InputSchema)
+// with the transforms applied. It returns nil if the schema is unknown.
+func TransformedSchema(configYml []byte) (*GallonSchema, error) {
+	var config GallonConfig[yaml.Node, yaml.Node]
+	if err := yaml.Unmarshal(configYml, &config); err != nil {
+		return nil, err
+	}
+
+	return transformedSchema(&config)
+}
+
+func transformedSchema(config *GallonConfig[yaml.Node, yaml.Node]) (*GallonSchema, error) {
+	schema, err := inputSchema(&config.In)
+	if err != nil || schema == nil {
+		return nil, err
+	}
+
+	for _, node := range config.Transforms {
+		schema, err = transformSchema(&node, *schema)
+		if err != nil || schema == nil {
+			return nil, err
+		}
+	}
+
+	return schema, nil
+}
+
+// transformSchema applies an element of `transforms` to the schema.
+// It returns nil if the schema after the transform is unknown (See SchemaTransformPlugin).
+func transformSchema(node *yaml.Node, schema GallonSchema) (*GallonSchema, error) {
+	var withType struct {
+		Type string `yaml:"type"`
+	}
+	if err := node.Decode(&withType); err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(TransformPlugins(), withType.Type) {
+		return nil, nil
+	}
+
+	configYml, err := yaml.Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+
+	plugin, err := NewTransformPluginFromConfig(withType.Type, configYml)
+	if err != nil {
+		return nil, err
+	}
+	defer plugin.Cleanup()
+
+	transform, ok := plugin.(SchemaTransformPlugin)
+	if !ok {
+		return nil, nil
+	}
+
+	result := transform.TransformSchema(schema)
+	return &result, nil
+}
+
+// bigQueryCompatibleTypes are the types of values which can be loaded into the BigQuery types.
+var bigQueryCompatibleTypes = map[bigquery.FieldType][]GallonType{
+	bigquery.StringFieldType:    {GallonTypeString, GallonTypeDate, GallonTypeTime, GallonTypeJSON, GallonTypeObject, GallonTypeArray, GallonTypeNumber, GallonTypeAny},
+	bigquery.IntegerFieldType:   {GallonTypeInt, GallonTypeAny},
+	bigquery.FloatFieldType:     {GallonTypeFloat, GallonTypeInt, GallonTypeAny},
+	bigquery.BooleanFieldType:   {GallonTypeBool, GallonTypeAny},
+	bigquery.TimestampFieldType: {GallonTypeTime, GallonTypeDate, GallonTypeAny},
+	bigquery.DateFieldType:      {GallonTypeDate, GallonTypeAny},
+	bigquery.RecordFieldType:    {GallonTypeObject, GallonTypeJSON, GallonTypeAny},
+	bigquery.JSONFieldType:      {GallonTypeJSON, GallonTypeObject, GallonTypeArray, GallonTypeAny},
+}
+
+// compareBigQuerySchema compares the fields of the records with the BigQuery schema.
+func compareBigQuerySchema(prefix string, fields []GallonField, outSchema bigquery.Schema) []SchemaIssue {
+	issues := []SchemaIssue{}
+
+	for _, out := range outSchema {
+		name := prefix + out.Name
+
+		i := slices.IndexFunc(fields, func(f GallonField) bool { return f.Name == out.Name })
+		if i < 0 {
+			issues = append(issues, SchemaIssue{
+				Column:  name,
+				Kind:    SchemaIssueNullColumn,
+				Message: fmt.Sprintf("not in the input, %v column is always NULL", out.Type),
+			})
+			continue
+		}
+		field := fields[i]
+
+		// a scalar cannot be loaded into a repeated column, while json and any values may be arrays
+		if out.Repeated && !slices.Contains([]GallonType{GallonTypeArray, GallonTypeJSON, GallonTypeAny}, field.Type) {
+			issues = append(issues, SchemaIssue{
+				Column:  name,
+				Kind:    SchemaIssueTypeMismatch,
+				Message: fmt.Sprintf("%v (scalar) cannot be loaded into REPEATED column", field.description()),
+			})
+			continue
+		}
+
+		// a repeated column is compared with the items of an array
+		if out.Repeated && field.Type == GallonTypeArray && field.Items != nil {
+			items := *field.Items
+			items.Name = field.Name
+			field = items
+		}
+
+		compatible, known := bigQueryCompatibleTypes[out.Type]
+		if known && !slices.Contains(compatible, field.Type) {
+			message := fmt.Sprintf("%v cannot be loaded into %v column", field.description(), out.Type)
+			if field.Type == GallonTypeNumber {
+				message += " (dynamodb number is extracted as a string, convert it with `cast` transform)"
+			}
+
+			issues = append(issues, SchemaIssue{
+				Column:  name,
+				Kind:    SchemaIssueTypeMismatch,
+				Message: message,
+			})
+			continue
+		}
+
+		// the fields of an object are checked only if they are known
+		if out.Type == bigquery.RecordFieldType && field.Type == GallonTypeObject {
+			issues = append(issues, compareBigQuerySchema(name+".", field.Fields, out.Schema)...)
+		}
+	}
+
+	for _, field := range fields {
+		if !slices.ContainsFunc(outSchema, func(f *bigquery.FieldSchema) bool { return f.Name == field.Name }) {
+			issues = append(issues, SchemaIssue{
+				Column:  prefix + field.Name,
+				Kind:    SchemaIssueMissingColumn,
+				Message: fmt.Sprintf("not in the output schema, %v is not loaded", field.description()),
+			})
+		}
+	}
+
+	return issues
+}
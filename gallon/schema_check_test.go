@@ -0,0 +1,210 @@
+package gallon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CheckSchemaCompatibility(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []SchemaIssue
+	}{
+		{
+			name: "dynamodb",
+			config: `
+in:
+  type: dynamodb
+  table: users
+  schema:
+    id:
+      type: string
+    age:
+      type: number
+    score:
+      type: number
+    nickname:
+      type: string
+      rename: name
+    address:
+      type: object
+      properties:
+        city:
+          type: string
+        zip:
+          type: number
+    memo:
+      type: string
+transforms:
+  - type: cast
+    columns:
+      score: float
+  - type: drop
+    columns:
+      - memo
+out:
+  type: bigquery
+  schema:
+    id:
+      type: string
+    age:
+      type: integer
+    score:
+      type: float
+    name:
+      type: string
+    address:
+      type: record
+      fields:
+        city:
+          type: string
+        country:
+          type: string
+    created_at:
+      type: timestamp
+`,
+			want: []SchemaIssue{
+				{
+					Output:  "out",
+					Column:  "age",
+					Kind:    SchemaIssueTypeMismatch,
+					Message: "dynamodb number cannot be loaded into INTEGER column (dynamodb number is extracted as a string, convert it with `cast` transform)",
+				},
+				{
+					Output:  "out",
+					Column:  "address.country",
+					Kind:    SchemaIssueNullColumn,
+					Message: "not in the input, STRING column is always NULL",
+				},
+				{
+					Output:  "out",
+					Column:  "address.zip",
+					Kind:    SchemaIssueMissingColumn,
+					Message: "not in the output schema, dynamodb number is not loaded",
+				},
+				{
+					Output:  "out",
+					Column:  "created_at",
+					Kind:    SchemaIssueNullColumn,
+					Message: "not in the input, TIMESTAMP column is always NULL",
+				},
+			},
+		},
+		{
+			name: "sql with multiple outputs",
+			config: `
+in:
+  type: sql
+  table: users
+  schema:
+    id:
+      type: int
+    created_at:
+      type: time
+      transforms:
+        - type: string
+          format: "2006-01-02"
+    updated_at:
+      type: time
+outs:
+  - type: file
+    filepath: ./users.jsonl
+  - type: bigquery
+    schema:
+      id:
+        type: integer
+      created_at:
+        type: timestamp
+      updated_at:
+        type: timestamp
+`,
+			want: []SchemaIssue{
+				{
+					Output:  "outs[1]",
+					Column:  "created_at",
+					Kind:    SchemaIssueTypeMismatch,
+					Message: "sql time (transformed to string) cannot be loaded into TIMESTAMP column",
+				},
+			},
+		},
+		{
+			name: "repeated",
+			config: `
+in:
+  type: dynamodb
+  table: users
+  schema:
+    tags:
+      type: array
+      items:
+        type: string
+    histories:
+      type: array
+      items:
+        type: object
+        properties:
+          at:
+            type: string
+          amount:
+            type: number
+    code:
+      type: string
+out:
+  type: bigquery
+  schema:
+    tags:
+      type: string
+      repeated: true
+    code:
+      type: string
+      repeated: true
+    histories:
+      type: record
+      repeated: true
+      fields:
+        at:
+          type: string
+        amount:
+          type: integer
+`,
+			want: []SchemaIssue{
+				{
+					Output:  "out",
+					Column:  "code",
+					Kind:    SchemaIssueTypeMismatch,
+					Message: "dynamodb string (scalar) cannot be loaded into REPEATED column",
+				},
+				{
+					Output:  "out",
+					Column:  "histories.amount",
+					Kind:    SchemaIssueTypeMismatch,
+					Message: "dynamodb number cannot be loaded into INTEGER column (dynamodb number is extracted as a string, convert it with `cast` transform)",
+				},
+			},
+		},
+		{
+			name: "raw query",
+			config: `
+in:
+  type: sql
+  query: SELECT * FROM users
+out:
+  type: bigquery
+  schema:
+    id:
+      type: integer
+`,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := CheckSchemaCompatibility([]byte(tt.config))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, issues)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package gallon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type schemaInputPluginStub struct {
+	*InputPluginStub
+	schema GallonSchema
+}
+
+var _ SchemaInputPlugin = &schemaInputPluginStub{}
+
+func (p *schemaInputPluginStub) Schema() *GallonSchema {
+	return &p.schema
+}
+
+// schemaOutputPluginStub records the schema set before Load
+type schemaOutputPluginStub struct {
+	schema *GallonSchema
+}
+
+var _ SchemaOutputPlugin = &schemaOutputPluginStub{}
+
+func (p *schemaOutputPluginStub) ReplaceLogger(logger logr.Logger) {
+}
+
+func (p *schemaOutputPluginStub) Cleanup() error {
+	return nil
+}
+
+func (p *schemaOutputPluginStub) SetSchema(schema GallonSchema) {
+	p.schema = &schema
+}
+
+func (p *schemaOutputPluginStub) Load(ctx context.Context, messages chan []GallonRecord, errs chan error) error {
+	for range messages {
+	}
+
+	return nil
+}
+
+func Test_schema_through_transforms(t *testing.T) {
+	newTransforms := func(configs ...string) []TransformPlugin {
+		transforms := []TransformPlugin{}
+		for _, config := range configs {
+			var withType struct {
+				Type string `yaml:"type"`
+			}
+			if err := yaml.Unmarshal([]byte(config), &withType); err != nil {
+				t.Fatalf("Could not parse config: %s", err)
+			}
+
+			transform, err := NewTransformPluginFromConfig(withType.Type, []byte(config))
+			if err != nil {
+				t.Fatalf("Could not create plugin: %s", err)
+			}
+
+			transforms = append(transforms, transform)
+		}
+
+		return transforms
+	}
+
+	schema := GallonSchema{Fields: []GallonField{
+		{Name: "id", Type: GallonTypeString},
+		{Name: "age", Type: GallonTypeNumber, Nullable: true},
+		{Name: "nickname", Type: GallonTypeString, Nullable: true},
+		{Name: "memo", Type: GallonTypeString, Nullable: true},
+	}}
+
+	tests := []struct {
+		name       string
+		transforms []TransformPlugin
+		want       *GallonSchema
+	}{
+		{
+			name: "no transforms",
+			want: &schema,
+		},
+		{
+			name: "transforms",
+			transforms: newTransforms(
+				"type: rename\ncolumns:\n  nickname: name",
+				"type: drop\ncolumns: [memo]",
+				"type: cast\ncolumns:\n  age: int",
+				"type: compute\ncolumn: label\ntemplate: '{{.id}}'",
+			),
+			want: &GallonSchema{Fields: []GallonField{
+				{Name: "id", Type: GallonTypeString},
+				{Name: "age", Type: GallonTypeInt, Nullable: true, source: "cast to int"},
+				{Name: "name", Type: GallonTypeString, Nullable: true},
+				{Name: "label", Type: GallonTypeString, source: "compute"},
+			}},
+		},
+		{
+			name:       "unknown transform",
+			transforms: []TransformPlugin{&transformPluginStub{}},
+			want:       nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := &schemaOutputPluginStub{}
+
+			g := Gallon{
+				Logger:     logger,
+				Input:      &schemaInputPluginStub{InputPluginStub: NewInputPluginStub(nil), schema: schema},
+				Output:     output,
+				Transforms: tt.transforms,
+			}
+
+			assert.NoError(t, g.Run(context.Background()))
+			assert.Equal(t, tt.want, output.schema)
+		})
+	}
+}
+
+// transformPluginStub does not implement SchemaTransformPlugin
+type transformPluginStub struct{}
+
+func (p *transformPluginStub) ReplaceLogger(logger logr.Logger) {
+}
+
+func (p *transformPluginStub) Cleanup() error {
+	return nil
+}
+
+func (p *transformPluginStub) Transform(ctx context.Context, records []GallonRecord, errs chan error) ([]GallonRecord, error) {
+	return records, nil
+}
+
+func Test_file_schema(t *testing.T) {
+	schema := GallonSchema{Fields: []GallonField{
+		{Name: "id", Type: GallonTypeInt},
+		{Name: "name", Type: GallonTypeString, Nullable: true},
+		{Name: "created_at", Type: GallonTypeTime},
+		{Name: "address", Type: GallonTypeObject, Nullable: true},
+	}}
+
+	r1 := NewGallonRecord()
+	r1.Set("created_at", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	r1.Set("id", int64(1))
+	r1.Set("name", "foo")
+	r1.Set("address", map[string]any{"city": "Tokyo"})
+
+	r2 := NewGallonRecord()
+	r2.Set("id", int64(2))
+	r2.Set("name", nil)
+	r2.Set("created_at", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			name: "csv",
+			config: `
+out:
+  type: file
+  format: csv
+  filepath: ./virtual
+  header: true
+`,
+			expected: `id,name,created_at,address
+1,foo,2024-01-02T03:04:05Z,"{""city"":""Tokyo""}"
+2,,2024-01-02T03:04:05Z,
+`,
+		},
+		{
+			name: "jsonl",
+			config: `
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual
+`,
+			expected: `{"id":1,"name":"foo","created_at":"2024-01-02T03:04:05Z","address":{"city":"Tokyo"}}
+{"id":2,"name":null,"created_at":"2024-01-02T03:04:05Z","address":null}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			writer := bufio.NewWriter(buf)
+
+			output, err := NewOutputPluginFileFromConfig([]byte(tt.config))
+			if err != nil {
+				t.Errorf("Could not create plugin: %s", err)
+			}
+			output.newWriter = func() (io.WriteCloser, error) {
+				return NewNopWriteCloser(writer), nil
+			}
+
+			g := Gallon{
+				Logger: logger,
+				Input: &schemaInputPluginStub{
+					InputPluginStub: NewInputPluginStub([][]GallonRecord{{r1, r2}}),
+					schema:          schema,
+				},
+				Output: output,
+			}
+
+			assert.NoError(t, g.Run(context.Background()))
+			assert.NoError(t, writer.Flush())
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
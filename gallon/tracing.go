@@ -0,0 +1,35 @@
+package gallon
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of migrations with the global TracerProvider (See otel.SetTracerProvider).
+// If it is not set, the spans are not recorded.
+var tracer = otel.Tracer("github.com/myuon/gallon/gallon")
+
+// endSpan ends the span, recording the error if any.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// startLoadBatchSpan starts a span for a batch loaded by the output plugin.
+func startLoadBatchSpan(ctx context.Context, plugin any, records int) (context.Context, trace.Span) {
+	name, table := metricLabelsOf(plugin)
+
+	return tracer.Start(ctx, "gallon.loadBatch", trace.WithAttributes(
+		attribute.String("gallon.plugin", name),
+		attribute.String("gallon.table", table),
+		attribute.Int("gallon.records", records),
+	))
+}
@@ -0,0 +1,63 @@
+package gallon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	// the global TracerProvider is bound to the tracer only once, so the tracer is replaced directly
+	original := tracer
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	defer func() {
+		tracer = original
+	}()
+
+	output, err := NewOutputPluginFileFromConfig([]byte(`
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual
+`))
+	if err != nil {
+		t.Errorf("Could not create plugin: %s", err)
+	}
+
+	writer := bufio.NewWriter(new(bytes.Buffer))
+	output.newWriter = func() (io.WriteCloser, error) {
+		return NewNopWriteCloser(writer), nil
+	}
+
+	r := NewGallonRecord()
+	r.Set("id", "1")
+
+	g := Gallon{
+		Logger: logger,
+		Input:  NewInputPluginStub([][]GallonRecord{{r}, {r, r}}),
+		Output: output,
+	}
+
+	if err := g.Run(context.Background()); err != nil {
+		t.Errorf("Could not run command: %s", err)
+	}
+
+	spans := recorder.Ended()
+	names := []string{}
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	assert.Equal(t, []string{"gallon.loadBatch", "gallon.loadBatch", "gallon.Run"}, names)
+
+	run := spans[2]
+	for _, span := range spans[:2] {
+		assert.Equal(t, run.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+}
@@ -0,0 +1,221 @@
+package gallon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v3"
+)
+
+// TransformPluginCast converts the values of columns into another type.
+// Supported types are `string`, `int`, `float`, `bool` and `time`. nil is kept as nil.
+type TransformPluginCast struct {
+	logger  logr.Logger
+	columns map[string]string
+	format  string
+}
+
+func NewTransformPluginCast(
+	columns map[string]string,
+	format string,
+) *TransformPluginCast {
+	return &TransformPluginCast{
+		columns: columns,
+		format:  format,
+	}
+}
+
+var _ TransformPlugin = &TransformPluginCast{}
+var _ SchemaTransformPlugin = &TransformPluginCast{}
+
+func (p *TransformPluginCast) ReplaceLogger(logger logr.Logger) {
+	p.logger = logger
+}
+
+func (p *TransformPluginCast) Cleanup() error {
+	return nil
+}
+
+func (p *TransformPluginCast) metricLabels() (string, string) {
+	return "cast", ""
+}
+
+func (p *TransformPluginCast) Transform(
+	ctx context.Context,
+	records []GallonRecord,
+	errs chan error,
+) ([]GallonRecord, error) {
+	results := []GallonRecord{}
+
+loop:
+	for _, record := range records {
+		result := NewGallonRecord()
+		for pair := record.asOrderdMap().Oldest(); pair != nil; pair = pair.Next() {
+			value := pair.Value
+
+			if to, ok := p.columns[pair.Key]; ok {
+				v, err := castValue(value, to, p.format)
+				if err != nil {
+					errs <- NewRecordError(&record, fmt.Errorf("failed to cast column: %v (error: %v)", pair.Key, err))
+					continue loop
+				}
+
+				value = v
+			}
+
+			result.Set(pair.Key, value)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (p *TransformPluginCast) TransformSchema(schema GallonSchema) GallonSchema {
+	fields := []GallonField{}
+	for _, field := range schema.Fields {
+		if to, ok := p.columns[field.Name]; ok {
+			field = GallonField{Name: field.Name, Type: GallonType(to), Nullable: field.Nullable, source: "cast to " + to}
+		}
+
+		fields = append(fields, field)
+	}
+
+	return GallonSchema{Fields: fields}
+}
+
+// castValue converts a value into the type. format is a Go time format used between `time` and `string`.
+func castValue(value any, to string, format string) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+
+	switch to {
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case time.Time:
+			return v.Format(format), nil
+		case map[string]any, []any, *GallonRecord:
+			bs, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+
+			return string(bs), nil
+		default:
+			return fmt.Sprintf("%v", v), nil
+		}
+	case "int":
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		case int32:
+			return int64(v), nil
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("value is not integer: %v", v)
+			}
+
+			return int64(v), nil
+		case bool:
+			if v {
+				return int64(1), nil
+			}
+
+			return int64(0), nil
+		case string:
+			return strconv.ParseInt(v, 10, 64)
+		case json.Number:
+			return v.Int64()
+		}
+	case "float":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case float32:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		case int32:
+			return float64(v), nil
+		case string:
+			return strconv.ParseFloat(v, 64)
+		case json.Number:
+			return v.Float64()
+		}
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		case int:
+			return v != 0, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case "time":
+		switch v := value.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			return time.Parse(format, v)
+		case int64:
+			return time.Unix(v, 0), nil
+		case int:
+			return time.Unix(int64(v), 0), nil
+		case float64:
+			return time.Unix(int64(v), 0), nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown type: %v", to)
+	}
+
+	return nil, fmt.Errorf("cannot cast %T to %v: %v", value, to, value)
+}
+
+type TransformPluginCastConfig struct {
+	// Columns is a map from the column name to the type
+	Columns map[string]string `yaml:"columns"`
+	// Format is a Go time format for `time` <-> `string` conversion (optional, default: RFC3339)
+	Format *string `yaml:"format"`
+}
+
+// NewTransformPluginCastFromConfig creates a plugin from an element of `transforms`.
+func NewTransformPluginCastFromConfig(configYml []byte) (*TransformPluginCast, error) {
+	var config TransformPluginCastConfig
+	if err := yaml.Unmarshal(configYml, &config); err != nil {
+		return nil, err
+	}
+
+	for column, to := range config.Columns {
+		switch to {
+		case "string", "int", "float", "bool", "time":
+		default:
+			return nil, fmt.Errorf("unknown type: %v for column: %v", to, column)
+		}
+	}
+
+	format := time.RFC3339
+	if config.Format != nil {
+		format = *config.Format
+	}
+
+	return NewTransformPluginCast(config.Columns, format), nil
+}
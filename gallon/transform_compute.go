@@ -0,0 +1,120 @@
+package gallon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"text/template"
+
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v3"
+)
+
+// TransformPluginCompute sets a column to a string computed from the record with Go's text/template.
+// The record is passed to the template as a map (e.g. `{{.first_name}} {{.last_name}}`).
+type TransformPluginCompute struct {
+	logger   logr.Logger
+	column   string
+	template *template.Template
+}
+
+func NewTransformPluginCompute(
+	column string,
+	template *template.Template,
+) *TransformPluginCompute {
+	return &TransformPluginCompute{
+		column:   column,
+		template: template,
+	}
+}
+
+var _ TransformPlugin = &TransformPluginCompute{}
+var _ SchemaTransformPlugin = &TransformPluginCompute{}
+
+func (p *TransformPluginCompute) ReplaceLogger(logger logr.Logger) {
+	p.logger = logger
+}
+
+func (p *TransformPluginCompute) Cleanup() error {
+	return nil
+}
+
+func (p *TransformPluginCompute) metricLabels() (string, string) {
+	return "compute", ""
+}
+
+func (p *TransformPluginCompute) Transform(
+	ctx context.Context,
+	records []GallonRecord,
+	errs chan error,
+) ([]GallonRecord, error) {
+	results := []GallonRecord{}
+	for _, record := range records {
+		data := map[string]any{}
+		for pair := record.asOrderdMap().Oldest(); pair != nil; pair = pair.Next() {
+			data[pair.Key] = pair.Value
+		}
+
+		var buf bytes.Buffer
+		if err := p.template.Execute(&buf, data); err != nil {
+			errs <- NewRecordError(&record, fmt.Errorf("failed to compute column: %v (error: %v)", p.column, err))
+			continue
+		}
+
+		result := NewGallonRecord()
+		for pair := record.asOrderdMap().Oldest(); pair != nil; pair = pair.Next() {
+			result.Set(pair.Key, pair.Value)
+		}
+		result.Set(p.column, buf.String())
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// TransformSchema sets the type of the column to string, adding it to the last if it does not exist.
+func (p *TransformPluginCompute) TransformSchema(schema GallonSchema) GallonSchema {
+	computed := GallonField{Name: p.column, Type: GallonTypeString, source: "compute"}
+
+	fields := []GallonField{}
+	found := false
+	for _, field := range schema.Fields {
+		if field.Name == p.column {
+			field = computed
+			found = true
+		}
+
+		fields = append(fields, field)
+	}
+	if !found {
+		fields = append(fields, computed)
+	}
+
+	return GallonSchema{Fields: fields}
+}
+
+type TransformPluginComputeConfig struct {
+	Column   string `yaml:"column"`
+	Template string `yaml:"template"`
+}
+
+// NewTransformPluginComputeFromConfig creates a plugin from an element of `transforms`.
+func NewTransformPluginComputeFromConfig(configYml []byte) (*TransformPluginCompute, error) {
+	var config TransformPluginComputeConfig
+	if err := yaml.Unmarshal(configYml, &config); err != nil {
+		return nil, err
+	}
+
+	if config.Column == "" {
+		return nil, errors.New("column is required for compute transform")
+	}
+
+	tmpl, err := template.New(config.Column).Option("missingkey=zero").Parse(config.Template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template for column: %v (error: %v)", config.Column, err)
+	}
+
+	return NewTransformPluginCompute(config.Column, tmpl), nil
+}
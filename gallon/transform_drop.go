@@ -0,0 +1,85 @@
+package gallon
+
+import (
+	"context"
+	"slices"
+
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v3"
+)
+
+// TransformPluginDrop removes columns from records.
+type TransformPluginDrop struct {
+	logger  logr.Logger
+	columns []string
+}
+
+func NewTransformPluginDrop(
+	columns []string,
+) *TransformPluginDrop {
+	return &TransformPluginDrop{
+		columns: columns,
+	}
+}
+
+var _ TransformPlugin = &TransformPluginDrop{}
+var _ SchemaTransformPlugin = &TransformPluginDrop{}
+
+func (p *TransformPluginDrop) ReplaceLogger(logger logr.Logger) {
+	p.logger = logger
+}
+
+func (p *TransformPluginDrop) Cleanup() error {
+	return nil
+}
+
+func (p *TransformPluginDrop) metricLabels() (string, string) {
+	return "drop", ""
+}
+
+func (p *TransformPluginDrop) Transform(
+	ctx context.Context,
+	records []GallonRecord,
+	errs chan error,
+) ([]GallonRecord, error) {
+	results := []GallonRecord{}
+	for _, record := range records {
+		result := NewGallonRecord()
+		for pair := record.asOrderdMap().Oldest(); pair != nil; pair = pair.Next() {
+			result.Set(pair.Key, pair.Value)
+		}
+
+		for _, column := range p.columns {
+			result.asOrderdMap().Delete(column)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (p *TransformPluginDrop) TransformSchema(schema GallonSchema) GallonSchema {
+	fields := []GallonField{}
+	for _, field := range schema.Fields {
+		if !slices.Contains(p.columns, field.Name) {
+			fields = append(fields, field)
+		}
+	}
+
+	return GallonSchema{Fields: fields}
+}
+
+type TransformPluginDropConfig struct {
+	Columns []string `yaml:"columns"`
+}
+
+// NewTransformPluginDropFromConfig creates a plugin from an element of `transforms`.
+func NewTransformPluginDropFromConfig(configYml []byte) (*TransformPluginDrop, error) {
+	var config TransformPluginDropConfig
+	if err := yaml.Unmarshal(configYml, &config); err != nil {
+		return nil, err
+	}
+
+	return NewTransformPluginDrop(config.Columns), nil
+}
@@ -0,0 +1,88 @@
+package gallon
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"gopkg.in/yaml.v3"
+)
+
+// TransformPluginRename renames columns, keeping the order of them.
+type TransformPluginRename struct {
+	logger  logr.Logger
+	columns map[string]string
+}
+
+func NewTransformPluginRename(
+	columns map[string]string,
+) *TransformPluginRename {
+	return &TransformPluginRename{
+		columns: columns,
+	}
+}
+
+var _ TransformPlugin = &TransformPluginRename{}
+var _ SchemaTransformPlugin = &TransformPluginRename{}
+
+func (p *TransformPluginRename) ReplaceLogger(logger logr.Logger) {
+	p.logger = logger
+}
+
+func (p *TransformPluginRename) Cleanup() error {
+	return nil
+}
+
+func (p *TransformPluginRename) metricLabels() (string, string) {
+	return "rename", ""
+}
+
+func (p *TransformPluginRename) Transform(
+	ctx context.Context,
+	records []GallonRecord,
+	errs chan error,
+) ([]GallonRecord, error) {
+	results := []GallonRecord{}
+	for _, record := range records {
+		result := NewGallonRecord()
+		for pair := record.asOrderdMap().Oldest(); pair != nil; pair = pair.Next() {
+			key := pair.Key
+			if renamed, ok := p.columns[key]; ok {
+				key = renamed
+			}
+
+			result.Set(key, pair.Value)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (p *TransformPluginRename) TransformSchema(schema GallonSchema) GallonSchema {
+	fields := []GallonField{}
+	for _, field := range schema.Fields {
+		if renamed, ok := p.columns[field.Name]; ok {
+			field.Name = renamed
+		}
+
+		fields = append(fields, field)
+	}
+
+	return GallonSchema{Fields: fields}
+}
+
+type TransformPluginRenameConfig struct {
+	// Columns is a map from the current column name to the new column name
+	Columns map[string]string `yaml:"columns"`
+}
+
+// NewTransformPluginRenameFromConfig creates a plugin from an element of `transforms`.
+func NewTransformPluginRenameFromConfig(configYml []byte) (*TransformPluginRename, error) {
+	var config TransformPluginRenameConfig
+	if err := yaml.Unmarshal(configYml, &config); err != nil {
+		return nil, err
+	}
+
+	return NewTransformPluginRename(config.Columns), nil
+}
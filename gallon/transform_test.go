@@ -0,0 +1,130 @@
+package gallon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_transforms(t *testing.T) {
+	rename, err := NewTransformPluginRenameFromConfig([]byte(`
+type: rename
+columns:
+  name: first_name
+`))
+	if err != nil {
+		t.Fatalf("Could not create plugin: %s", err)
+	}
+
+	cast, err := NewTransformPluginCastFromConfig([]byte(`
+type: cast
+columns:
+  age: int
+`))
+	if err != nil {
+		t.Fatalf("Could not create plugin: %s", err)
+	}
+
+	compute, err := NewTransformPluginComputeFromConfig([]byte(`
+type: compute
+column: greeting
+template: "Hello, {{.first_name}}"
+`))
+	if err != nil {
+		t.Fatalf("Could not create plugin: %s", err)
+	}
+
+	drop, err := NewTransformPluginDropFromConfig([]byte(`
+type: drop
+columns:
+  - secret
+`))
+	if err != nil {
+		t.Fatalf("Could not create plugin: %s", err)
+	}
+
+	output, err := NewOutputPluginFileFromConfig([]byte(`
+out:
+  type: file
+  format: jsonl
+  filepath: ./virtual
+`))
+	if err != nil {
+		t.Fatalf("Could not create plugin: %s", err)
+	}
+
+	buf := new(bytes.Buffer)
+	writer := bufio.NewWriter(buf)
+	output.newWriter = func() (io.WriteCloser, error) {
+		return NewNopWriteCloser(writer), nil
+	}
+
+	r1 := NewGallonRecord()
+	r1.Set("id", "1")
+	r1.Set("name", "foo")
+	r1.Set("age", "20")
+	r1.Set("secret", "xxx")
+
+	r2 := NewGallonRecord()
+	r2.Set("id", "2")
+	r2.Set("name", "bar")
+	r2.Set("age", "unknown")
+	r2.Set("secret", "yyy")
+
+	g := Gallon{
+		Logger:     logger,
+		Input:      NewInputPluginStub([][]GallonRecord{{r1, r2}}),
+		Output:     output,
+		Transforms: []TransformPlugin{rename, cast, compute, drop},
+	}
+
+	result, err := g.RunWithResult(context.Background())
+	if err != nil {
+		t.Errorf("Could not run command: %s", err)
+	}
+
+	expected := `{"id":"1","first_name":"foo","age":20,"greeting":"Hello, foo"}
+`
+	assert.Equal(t, expected, buf.String())
+	assert.Equal(t, 2, result.ExtractedRecords)
+	assert.Equal(t, 1, result.LoadedRecords)
+	assert.Equal(t, 1, result.RejectedRecords)
+}
+
+func Test_castValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		to      string
+		want    any
+		wantErr bool
+	}{
+		{name: "dynamodb number to int", value: "42", to: "int", want: int64(42)},
+		{name: "float to int", value: float64(3), to: "int", want: int64(3)},
+		{name: "non integral float to int", value: 3.5, to: "int", wantErr: true},
+		{name: "bytes to float", value: []byte("1.5"), to: "float", want: 1.5},
+		{name: "int to string", value: int64(10), to: "string", want: "10"},
+		{name: "map to string", value: map[string]any{"a": 1}, to: "string", want: `{"a":1}`},
+		{name: "string to bool", value: "true", to: "bool", want: true},
+		{name: "nil", value: nil, to: "int", want: nil},
+		{name: "unknown type", value: "1", to: "decimal", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := castValue(tt.value, tt.to, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("castValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,446 @@
+package gallon
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigError is a problem in the config yaml found by ValidateConfig.
+type ConfigError struct {
+	// Line and Column are the position in the config yaml (1-based), or 0 if unknown.
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+
+	return fmt.Sprintf("line %v: %v", e.Line, e.Message)
+}
+
+// The config types of the built-in plugins, whose unknown fields are reported by ValidateConfig.
+// The fields of the plugins registered by other packages are not checked.
+var (
+	inputConfigTypes = map[string]reflect.Type{
+		"sql":      reflect.TypeFor[InputPluginSqlConfig](),
+		"dynamodb": reflect.TypeFor[InputPluginDynamoDbConfig](),
+		"random":   reflect.TypeFor[InputPluginRandomConfig](),
+		"exec":     reflect.TypeFor[ExecConfig](),
+	}
+	outputConfigTypes = map[string]reflect.Type{
+		"bigquery": reflect.TypeFor[OutputPluginBigQueryConfig](),
+		"file":     reflect.TypeFor[OutputPluginFileConfig](),
+		"stdout":   reflect.TypeFor[OutputPluginStdoutConfig](),
+		"exec":     reflect.TypeFor[ExecConfig](),
+	}
+	transformConfigTypes = map[string]reflect.Type{
+		"rename":  reflect.TypeFor[TransformPluginRenameConfig](),
+		"drop":    reflect.TypeFor[TransformPluginDropConfig](),
+		"cast":    reflect.TypeFor[TransformPluginCastConfig](),
+		"compute": reflect.TypeFor[TransformPluginComputeConfig](),
+	}
+)
+
+// columnRule is the column types accepted by the schema of a plugin.
+type columnRule struct {
+	// types are the types accepted by getValue (or getType) of the plugin
+	types []string
+	// fold is true if the types are case-insensitive
+	fold bool
+	// fields is the key of the nested columns, e.g. `properties` of a dynamodb object
+	fields string
+	// items is the key of the column of the items of an array
+	items string
+	// requires are the keys required by the types, e.g. `items` of a dynamodb array
+	requires map[string]string
+}
+
+var (
+	sqlColumnRule = columnRule{
+		types: sqlColumnTypes,
+	}
+	dynamoDbColumnRule = columnRule{
+		types:    dynamoDbColumnTypes,
+		fields:   "properties",
+		items:    "items",
+		requires: map[string]string{"array": "items"},
+	}
+	randomColumnRule = columnRule{
+		types:  randomColumnTypes,
+		fields: "fields",
+	}
+	bigQueryColumnRule = columnRule{
+		types:    bigQueryTypeNames(),
+		fold:     true,
+		fields:   "fields",
+		requires: map[string]string{string(bigquery.RecordFieldType): "fields"},
+	}
+
+	schemaColumnRules = map[reflect.Type]columnRule{
+		reflect.TypeFor[InputPluginSqlConfig]():       sqlColumnRule,
+		reflect.TypeFor[InputPluginDynamoDbConfig]():  dynamoDbColumnRule,
+		reflect.TypeFor[InputPluginRandomConfig]():    randomColumnRule,
+		reflect.TypeFor[OutputPluginBigQueryConfig](): bigQueryColumnRule,
+	}
+)
+
+// bigQueryTypeNames returns the types accepted by getType, followed by the aliases.
+func bigQueryTypeNames() []string {
+	names := []string{}
+	for _, t := range bigQueryFieldTypes {
+		names = append(names, string(t))
+	}
+
+	return append(names, slices.Sorted(maps.Keys(bigQueryFieldTypeAliases))...)
+}
+
+// ValidateConfig checks the config yaml without creating the input and output plugins, so no connection is made.
+// It reports the unknown fields of the built-in plugins (which are silently ignored by yaml.Unmarshal),
+// the unknown plugin types, the unknown column types in the schemas, the errors of the built-in transforms,
+// and the invalid values of `onOutputFailure`, `rateLimit`, `batch` and `verify`.
+func ValidateConfig(configYml []byte) []ConfigError {
+	var root yaml.Node
+	if err := yaml.Unmarshal(configYml, &root); err != nil {
+		return yamlConfigErrors(err)
+	}
+	if len(root.Content) == 0 {
+		return []ConfigError{{Message: "config is empty"}}
+	}
+
+	v := &configValidator{}
+	doc := root.Content[0]
+
+	v.decode(doc, reflect.TypeFor[GallonConfig[yaml.Node, yaml.Node]]())
+	v.knownFields(doc, reflect.TypeFor[GallonConfig[yaml.Node, yaml.Node]](), "")
+
+	if in := mappingValue(doc, "in"); in != nil {
+		v.plugin(in, "in", InputPlugins(), inputConfigTypes)
+	} else {
+		v.errorf(doc, "in is required")
+	}
+
+	if out := mappingValue(doc, "out"); out != nil {
+		v.plugin(out, "out", OutputPlugins(), outputConfigTypes)
+	}
+	if outs := mappingValue(doc, "outs"); outs != nil && outs.Kind == yaml.SequenceNode {
+		for i, out := range outs.Content {
+			v.plugin(out, fmt.Sprintf("outs[%v]", i), OutputPlugins(), outputConfigTypes)
+		}
+	}
+	if mappingValue(doc, "out") == nil && mappingValue(doc, "outs") == nil {
+		v.errorf(doc, "out or outs is required")
+	}
+
+	if deadLetter := mappingValue(doc, "deadLetter"); deadLetter != nil {
+		v.plugin(deadLetter, "deadLetter", OutputPlugins(), outputConfigTypes)
+	}
+
+	// the policies are checked in the same way as the migration
+	var config GallonConfig[yaml.Node, yaml.Node]
+	if err := doc.Decode(&config); err == nil {
+		v.policy(doc, "onOutputFailure", config.OnOutputFailure.Validate())
+		v.policy(doc, "rateLimit", config.RateLimit.Validate())
+		v.policy(doc, "batch", config.Batch.Validate())
+		if config.Verify != nil {
+			v.policy(doc, "verify", config.Verify.Validate())
+		}
+	}
+
+	if checkpoint := mappingValue(doc, "checkpoint"); checkpoint != nil {
+		var config CheckpointConfig
+		if err := checkpoint.Decode(&config); err == nil {
+			if _, err := NewCheckpointStoreFromConfig(config); err != nil {
+				v.errorf(checkpoint, "checkpoint: %v", err)
+			}
+		}
+	}
+
+	if transforms := mappingValue(doc, "transforms"); transforms != nil && transforms.Kind == yaml.SequenceNode {
+		for i, transform := range transforms.Content {
+			v.plugin(transform, fmt.Sprintf("transforms[%v]", i), TransformPlugins(), transformConfigTypes)
+		}
+	}
+
+	return v.errs
+}
+
+type configValidator struct {
+	errs []ConfigError
+}
+
+func (v *configValidator) errorf(node *yaml.Node, format string, args ...any) {
+	v.errs = append(v.errs, ConfigError{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+// decode reports the type errors of the node decoded as t.
+func (v *configValidator) decode(node *yaml.Node, t reflect.Type) {
+	if err := node.Decode(reflect.New(t).Interface()); err != nil {
+		v.errs = append(v.errs, yamlConfigErrors(err)...)
+	}
+}
+
+// policy reports the error of the section of a policy, e.g. `rateLimit`.
+func (v *configValidator) policy(doc *yaml.Node, key string, err error) {
+	if err == nil {
+		return
+	}
+
+	node := mappingValue(doc, key)
+	if node == nil {
+		node = doc
+	}
+
+	v.errorf(node, "%v", err)
+}
+
+// plugin validates a plugin section, e.g. `in` or an element of `transforms`.
+func (v *configValidator) plugin(node *yaml.Node, path string, registered []string, configTypes map[string]reflect.Type) {
+	if node.Kind != yaml.MappingNode {
+		v.errorf(node, "%v must be a mapping", path)
+		return
+	}
+
+	typeNode := mappingValue(node, "type")
+	if typeNode == nil {
+		v.errorf(node, "type is required in %v", path)
+		return
+	}
+
+	pluginType := typeNode.Value
+	if !slices.Contains(registered, pluginType) {
+		v.errorf(typeNode, "plugin not found: %v (available: %v)", pluginType, strings.Join(registered, ", "))
+		return
+	}
+
+	t, ok := configTypes[pluginType]
+	if !ok {
+		return
+	}
+
+	errs := len(v.errs)
+	v.decode(node, t)
+	v.knownFields(node, t, path, "type")
+	if len(v.errs) > errs {
+		return
+	}
+
+	if rule, ok := schemaColumnRules[t]; ok {
+		v.columns(mappingValue(node, "schema"), path+".schema", rule)
+	}
+
+	// the built-in transforms make no connection, so the config is checked by creating the plugin
+	if transformConfigTypes[pluginType] == t {
+		configYml, err := yaml.Marshal(node)
+		if err != nil {
+			v.errorf(node, "%v: %v", path, err)
+			return
+		}
+
+		if _, err := NewTransformPluginFromConfig(pluginType, configYml); err != nil {
+			v.errorf(node, "%v: %v", path, err)
+		}
+	}
+}
+
+// columns checks the types of the columns in a schema.
+func (v *configValidator) columns(node *yaml.Node, path string, rule columnRule) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		v.column(node.Content[i+1], path+"."+node.Content[i].Value, rule)
+	}
+}
+
+func (v *configValidator) column(node *yaml.Node, path string, rule columnRule) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	typeNode := mappingValue(node, "type")
+	if typeNode == nil {
+		v.errorf(node, "type is required in %v", path)
+		return
+	}
+
+	i := slices.IndexFunc(rule.types, func(t string) bool {
+		return t == typeNode.Value || rule.fold && strings.EqualFold(t, typeNode.Value)
+	})
+	if i < 0 {
+		v.errorf(typeNode, "unknown type %q in %v (available: %v)", typeNode.Value, path, strings.Join(rule.types, ", "))
+		return
+	}
+
+	if key, ok := rule.requires[rule.types[i]]; ok && mappingValue(node, key) == nil {
+		v.errorf(typeNode, "%v is required for type %v in %v", key, typeNode.Value, path)
+	}
+
+	if rule.fields != "" {
+		v.columns(mappingValue(node, rule.fields), path+"."+rule.fields, rule)
+	}
+	if rule.items != "" {
+		if items := mappingValue(node, rule.items); items != nil {
+			v.column(items, path+"."+rule.items, rule)
+		}
+	}
+}
+
+// knownFields reports the keys of the node which are not the fields of t, except for the extra keys.
+func (v *configValidator) knownFields(node *yaml.Node, t reflect.Type, path string, extra ...string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeFor[yaml.Node]() {
+		return
+	}
+
+	// orderedmap.OrderedMap decodes itself as a mapping, whose value type is the result of Get
+	if get, ok := reflect.PointerTo(t).MethodByName("Get"); ok && strings.HasPrefix(t.Name(), "OrderedMap[") {
+		t = reflect.MapOf(get.Type.In(1), get.Type.Out(0))
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if slices.Contains(extra, key.Value) {
+				continue
+			}
+
+			field, ok := fields[key.Value]
+			if !ok {
+				message := fmt.Sprintf("unknown field %q in %v", key.Value, configPath(path))
+				for name := range fields {
+					if strings.EqualFold(name, key.Value) {
+						message += fmt.Sprintf(" (did you mean %q?)", name)
+					}
+				}
+
+				v.errorf(key, "%v", message)
+				continue
+			}
+
+			v.knownFields(node.Content[i+1], field, joinConfigPath(path, key.Value))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.knownFields(node.Content[i+1], t.Elem(), joinConfigPath(path, node.Content[i].Value))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+
+		for i, item := range node.Content {
+			v.knownFields(item, t.Elem(), fmt.Sprintf("%v[%v]", path, i))
+		}
+	}
+}
+
+// yamlFields returns the types of the fields of the struct by the keys in yaml.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		if slices.Contains(tag[1:], "inline") {
+			for name, ft := range yamlFields(f.Type) {
+				fields[name] = ft
+			}
+			continue
+		}
+
+		name := tag[0]
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		fields[name] = f.Type
+	}
+
+	return fields
+}
+
+// mappingValue returns the value of the key in the mapping node, or nil if it is not found.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func joinConfigPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func configPath(path string) string {
+	if path == "" {
+		return "the top level"
+	}
+
+	return path
+}
+
+var yamlLineRegexp = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlConfigErrors converts an error of yaml.v3, whose messages start with `line N:`.
+func yamlConfigErrors(err error) []ConfigError {
+	messages := []string{err.Error()}
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	}
+
+	errs := []ConfigError{}
+	for _, message := range messages {
+		m := yamlLineRegexp.FindStringSubmatch(message)
+		if m == nil {
+			errs = append(errs, ConfigError{Message: message})
+			continue
+		}
+
+		line, _ := strconv.Atoi(m[1])
+		errs = append(errs, ConfigError{Line: line, Message: m[2]})
+	}
+
+	return errs
+}
@@ -0,0 +1,209 @@
+package gallon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validate_config(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{
+			name: "valid",
+			config: `
+in:
+  type: sql
+  driver: mysql
+  table: users
+  pageSize: 100
+  schema:
+    id:
+      type: int
+    created_at:
+      type: int
+      transforms:
+        - type: time
+out:
+  type: bigquery
+  projectId: test
+  datasetId: test
+  tableId: users
+  location: asia-northeast1
+  schema:
+    id:
+      type: integer
+    created_at:
+      type: TIMESTAMP
+transforms:
+  - type: cast
+    columns:
+      id: string
+checkpoint:
+  type: file
+  path: ./state.json
+`,
+			expected: nil,
+		},
+		{
+			name: "unknown fields",
+			config: `
+in:
+  type: dynamodb
+  table: users
+  pagesize: 100
+  schema:
+    id:
+      type: string
+      renmae: user_id
+out:
+  type: stdout
+  format: json
+batch:
+  sise: 100
+`,
+			expected: []string{
+				`line 14: unknown field "sise" in batch`,
+				`line 5: unknown field "pagesize" in in (did you mean "pageSize"?)`,
+				`line 9: unknown field "renmae" in in.schema.id`,
+			},
+		},
+		{
+			name: "unknown types",
+			config: `
+in:
+  type: dynamodb
+  table: users
+  schema:
+    id:
+      type: str
+    tags:
+      type: array
+    address:
+      type: object
+      properties:
+        city:
+          type: text
+outs:
+  - type: bigquery
+    projectId: test
+    datasetId: test
+    tableId: users
+    schema:
+      id:
+        type: VARCHAR
+      address:
+        type: RECORD
+  - type: s3
+transforms:
+  - type: cast
+    columns:
+      id: uuid
+`,
+			expected: []string{
+				`line 7: unknown type "str" in in.schema.id (available: string, number, boolean, object, array, any)`,
+				`line 9: items is required for type array in in.schema.tags`,
+				`line 14: unknown type "text" in in.schema.address.properties.city (available: string, number, boolean, object, array, any)`,
+				`line 22: unknown type "VARCHAR" in outs[0].schema.id (available: STRING, INTEGER, FLOAT, BOOLEAN, TIMESTAMP, DATE, RECORD, JSON, BOOL, FLOAT64, INT64)`,
+				`line 24: fields is required for type RECORD in outs[0].schema.address`,
+				`line 25: plugin not found: s3 (available: bigquery, exec, file, stdout)`,
+				`line 27: transforms[0]: unknown type: uuid for column: id`,
+			},
+		},
+		{
+			name: "wrong value types",
+			config: `
+in:
+  type: random
+  pageSize: many
+out:
+  type: stdout
+`,
+			expected: []string{
+				"line 4: cannot unmarshal !!str `many` into int",
+			},
+		},
+		{
+			name: "missing sections",
+			config: `
+in:
+  table: users
+`,
+			expected: []string{
+				"line 3: type is required in in",
+				"line 2: out or outs is required",
+			},
+		},
+		{
+			name: "invalid policies",
+			config: `
+in:
+  type: random
+out:
+  type: stdout
+onOutputFailure: retry
+rateLimit:
+  recordsPerSecond: -1
+batch:
+  size: -10
+verify:
+  tolerance: -1
+`,
+			expected: []string{
+				"line 6: unknown onOutputFailure: retry",
+				"line 8: rateLimit must not be negative",
+				"line 10: batch must not be negative",
+				"line 12: verify tolerance must not be negative",
+			},
+		},
+		{
+			name:   "syntax error",
+			config: "in:\n  type: sql\n   table: users\n",
+			expected: []string{
+				"line 3: mapping values are not allowed in this context",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var messages []string
+			for _, err := range ValidateConfig([]byte(tt.config)) {
+				messages = append(messages, err.Error())
+			}
+
+			assert.Equal(t, tt.expected, messages)
+		})
+	}
+}
+
+// Test_column_types checks that the column types accepted by ValidateConfig (and the JSON Schema) are handled by the plugins.
+func Test_column_types(t *testing.T) {
+	for _, columnType := range sqlColumnTypes {
+		_, err := InputPluginSqlConfigSchemaColumn{Type: columnType}.getValue(struct{}{})
+		assert.NotContains(t, err.Error(), "unknown column type", columnType)
+	}
+
+	for _, columnType := range dynamoDbColumnTypes {
+		column := InputPluginDynamoDbConfigSchemaColumn{Type: columnType, Items: &InputPluginDynamoDbConfigSchemaColumn{Type: "string"}}
+		_, err := column.getValue(&types.AttributeValueMemberNULL{Value: true})
+		if err != nil {
+			assert.False(t, strings.HasPrefix(err.Error(), "unsupported type: "+columnType), columnType)
+		}
+	}
+
+	for _, columnType := range randomColumnTypes {
+		_, err := InputPluginRandomConfigSchemaColumn{Type: columnType}.generateValue(0)
+		assert.NoError(t, err, columnType)
+	}
+
+	for _, columnType := range bigQueryColumnRule.types {
+		_, err := getType(strings.ToLower(columnType))
+		assert.NoError(t, err, columnType)
+	}
+}
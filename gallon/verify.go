@@ -0,0 +1,108 @@
+package gallon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrVerificationFailed is returned by Run when the number of records in an output differs from the source beyond VerifyPolicy.
+var ErrVerificationFailed = errors.New("verification failed")
+
+// CountingInputPlugin is an InputPlugin which can count the records in the source, for VerifyPolicy.
+type CountingInputPlugin interface {
+	InputPlugin
+
+	// Count returns the number of records in the source. It is called after Extract has finished.
+	Count(ctx context.Context) (int, error)
+}
+
+// CountingOutputPlugin is an OutputPlugin which can count the records written to the destination, for VerifyPolicy.
+type CountingOutputPlugin interface {
+	OutputPlugin
+
+	// Count returns the number of records in the destination. It is called after Load has finished.
+	Count(ctx context.Context) (int, error)
+}
+
+// VerifyPolicy compares the number of records in the source with the number of records in each output after the migration.
+// The input and the outputs must implement CountingInputPlugin and CountingOutputPlugin.
+//
+// The rejected records are not excluded, so that they are counted as the difference.
+type VerifyPolicy struct {
+	// Tolerance is the maximum difference of the numbers. (default: 0)
+	Tolerance int `yaml:"tolerance"`
+	// TolerancePercentage is the maximum difference in percentage (0-100) of the number of records in the source. (optional)
+	// The difference is tolerated if it is within either Tolerance or TolerancePercentage.
+	TolerancePercentage *float64 `yaml:"tolerancePercentage"`
+}
+
+// Validate returns an error if the tolerances are negative.
+func (p VerifyPolicy) Validate() error {
+	if p.Tolerance < 0 || (p.TolerancePercentage != nil && *p.TolerancePercentage < 0) {
+		return errors.New("verify tolerance must not be negative")
+	}
+
+	return nil
+}
+
+func (p VerifyPolicy) tolerates(source int, written int) bool {
+	diff := source - written
+	if diff < 0 {
+		diff = -diff
+	}
+
+	if diff <= p.Tolerance {
+		return true
+	}
+
+	if p.TolerancePercentage != nil && source > 0 {
+		return float64(diff)/float64(source)*100 <= *p.TolerancePercentage
+	}
+
+	return false
+}
+
+// Verification is the result of VerifyPolicy.
+type Verification struct {
+	SourceRecords int
+	// OutputRecords is the number of records in each output, in the order of Gallon.Output and Gallon.Outputs.
+	OutputRecords []int
+}
+
+func (g *Gallon) verify(ctx context.Context) (*Verification, error) {
+	input, ok := g.Input.(CountingInputPlugin)
+	if !ok {
+		return nil, &GallonError{Stage: GallonStageVerify, Err: fmt.Errorf("input plugin does not support verify: %T", g.Input)}
+	}
+
+	source, err := input.Count(ctx)
+	if err != nil {
+		return nil, &GallonError{Stage: GallonStageVerify, Err: fmt.Errorf("failed to count records in the source: %w", err)}
+	}
+
+	verification := &Verification{SourceRecords: source}
+
+	var failures []error
+	for i, output := range g.outputs() {
+		counter, ok := output.(CountingOutputPlugin)
+		if !ok {
+			return verification, &GallonError{Stage: GallonStageVerify, Err: outputErr(i, fmt.Errorf("output plugin does not support verify: %T", output))}
+		}
+
+		written, err := counter.Count(ctx)
+		if err != nil {
+			return verification, &GallonError{Stage: GallonStageVerify, Err: outputErr(i, fmt.Errorf("failed to count records in the destination: %w", err))}
+		}
+
+		verification.OutputRecords = append(verification.OutputRecords, written)
+
+		if !g.Verify.tolerates(source, written) {
+			failures = append(failures, outputErr(i, fmt.Errorf("%w: %v records in the destination, but %v records in the source", ErrVerificationFailed, written, source)))
+		}
+	}
+
+	g.Logger.Info("verified", "source", source, "outputs", verification.OutputRecords)
+
+	return verification, errors.Join(failures...)
+}
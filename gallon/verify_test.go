@@ -0,0 +1,158 @@
+package gallon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingInputPluginStub struct {
+	*InputPluginStub
+	count int
+}
+
+var _ CountingInputPlugin = &countingInputPluginStub{}
+
+func (p *countingInputPluginStub) Count(ctx context.Context) (int, error) {
+	return p.count, nil
+}
+
+func Test_verify(t *testing.T) {
+	data := [][]GallonRecord{}
+	for page := 0; page < 3; page++ {
+		records := []GallonRecord{}
+		for i := 0; i < 2; i++ {
+			r := NewGallonRecord()
+			r.Set("id", fmt.Sprintf("%v-%v", page, i))
+
+			records = append(records, r)
+		}
+
+		data = append(data, records)
+	}
+
+	percentage := 25.0
+
+	tests := []struct {
+		name     string
+		source   int
+		policy   VerifyPolicy
+		expected error
+	}{
+		{
+			name:   "same counts",
+			source: 6,
+		},
+		{
+			name:     "different counts",
+			source:   8,
+			expected: ErrVerificationFailed,
+		},
+		{
+			name:   "within tolerance",
+			source: 8,
+			policy: VerifyPolicy{Tolerance: 2},
+		},
+		{
+			name:   "within tolerance percentage",
+			source: 8,
+			policy: VerifyPolicy{TolerancePercentage: &percentage},
+		},
+		{
+			name:     "beyond tolerance percentage",
+			source:   9,
+			policy:   VerifyPolicy{TolerancePercentage: &percentage},
+			expected: ErrVerificationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := NewOutputPluginFileFromConfig([]byte(fmt.Sprintf(`
+out:
+  type: file
+  format: jsonl
+  filepath: %v
+`, filepath.Join(t.TempDir(), "output.jsonl"))))
+			if err != nil {
+				t.Errorf("Could not create plugin: %s", err)
+			}
+
+			g := Gallon{
+				Logger: logger,
+				Input:  &countingInputPluginStub{InputPluginStub: NewInputPluginStub(data), count: tt.source},
+				Output: output,
+				Verify: &tt.policy,
+			}
+
+			result, err := g.RunWithResult(context.Background())
+			if tt.expected != nil {
+				assert.ErrorIs(t, err, tt.expected)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, &Verification{SourceRecords: tt.source, OutputRecords: []int{6}}, result.Verification)
+		})
+	}
+
+	t.Run("input without count", func(t *testing.T) {
+		output, err := NewOutputPluginFileFromConfig([]byte(fmt.Sprintf(`
+out:
+  type: file
+  format: jsonl
+  filepath: %v
+`, filepath.Join(t.TempDir(), "output.jsonl"))))
+		if err != nil {
+			t.Errorf("Could not create plugin: %s", err)
+		}
+
+		g := Gallon{
+			Logger: logger,
+			Input:  NewInputPluginStub(data),
+			Output: output,
+			Verify: &VerifyPolicy{},
+		}
+
+		err = g.Run(context.Background())
+
+		var gallonErr *GallonError
+		assert.True(t, errors.As(err, &gallonErr))
+		assert.Equal(t, GallonStageVerify, gallonErr.Stage)
+	})
+}
+
+func Test_file_count(t *testing.T) {
+	record := NewGallonRecord()
+	record.Set("id", "1")
+	record.Set("text", "multiple\nlines")
+
+	for _, format := range []string{"jsonl", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			output, err := NewOutputPluginFileFromConfig([]byte(fmt.Sprintf(`
+out:
+  type: file
+  format: %v
+  filepath: %v
+`, format, filepath.Join(t.TempDir(), "output."+format))))
+			if err != nil {
+				t.Errorf("Could not create plugin: %s", err)
+			}
+			output.ReplaceLogger(logger)
+
+			messages := make(chan []GallonRecord, 1)
+			messages <- []GallonRecord{record, record, record}
+			close(messages)
+
+			assert.NoError(t, output.Load(context.Background(), messages, make(chan error)))
+
+			count, err := output.Count(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, 3, count)
+		})
+	}
+}
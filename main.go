@@ -5,17 +5,23 @@
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/myuon/gallon/cmd"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"os"
 )
 
 var roomCmd = &cobra.Command{
 	Use:   "gallon",
 	Short: "Gallon is a tool for data migration",
 	Long:  `Gallon is a tool for data migration`,
+	// Errors are logged in main
+	SilenceErrors: true,
 }
 
 func chooseLogger(env string) (*zap.Logger, error) {
@@ -35,9 +41,34 @@ func main() {
 	zap.ReplaceGlobals(zapLog)
 
 	roomCmd.AddCommand(cmd.RunCmd)
+	roomCmd.AddCommand(cmd.ReplayCmd)
+	roomCmd.AddCommand(cmd.PluginsCmd)
+	roomCmd.AddCommand(cmd.ValidateCmd)
+	roomCmd.AddCommand(cmd.InitCmd)
+	roomCmd.AddCommand(cmd.PreviewCmd)
+	roomCmd.AddCommand(cmd.ConfigCmd)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first signal cancels the migration gracefully, and the second one forces exit
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		zap.S().Warnw("Received signal, shutting down gracefully. Send it again to force exit", "signal", sig.String())
+		cancel()
+
+		sig = <-signals
+		zap.S().Errorw("Received signal again, exiting", "signal", sig.String())
+		_ = zapLog.Sync()
+		os.Exit(130)
+	}()
 
-	if err := roomCmd.Execute(); err != nil {
+	if err := roomCmd.ExecuteContext(ctx); err != nil {
 		zap.S().Error(err)
+		_ = zapLog.Sync()
+		os.Exit(1)
 	}
 }
 